@@ -0,0 +1,70 @@
+//go:build ignore
+
+// gen_defaults generates defaults.go, the default values of the Step inputs, from the inputs of step.yml.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	if err := generate("../../step.yml", "defaults.go"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func generate(stepPth, outPth string) error {
+	content, err := os.ReadFile(stepPth)
+	if err != nil {
+		return fmt.Errorf("failed to read step.yml, error: %w", err)
+	}
+	var step struct {
+		Inputs []map[string]interface{} `yaml:"inputs"`
+	}
+	if err := yaml.Unmarshal(content, &step); err != nil {
+		return fmt.Errorf("failed to parse step.yml, error: %w", err)
+	}
+
+	defaults := map[string]string{}
+	for _, input := range step.Inputs {
+		for key, value := range input {
+			// The commands changing a track require it explicitly, and the Bitrise environment variables are not set
+			// outside of Bitrise.
+			if key == "opts" || key == "track" || value == nil {
+				continue
+			}
+			if s := fmt.Sprint(value); s != "" && !strings.HasPrefix(s, "$") {
+				defaults[key] = s
+			}
+		}
+	}
+	var keys []string
+	for key := range defaults {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by gen_defaults.go from step.yml; DO NOT EDIT.\n\npackage main\n\n")
+	b.WriteString("// defaultInputs are the default values of the Step inputs (see step.yml), except the ones referring to Bitrise\n")
+	b.WriteString("// environment variables, and the track, which the commands changing a track require explicitly.\n")
+	b.WriteString("var defaultInputs = map[string]string{\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "\t%q: %q,\n", key, defaults[key])
+	}
+	b.WriteString("}\n")
+
+	formatted, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format defaults, error: %w", err)
+	}
+	return os.WriteFile(outPth, formatted, 0644)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-steputils/stepconf"
+	"github.com/bitrise-steplib/steps-google-play-deploy/googleplay"
+	"gopkg.in/yaml.v3"
+)
+
+//go:generate go run gen_defaults.go
+
+// inputs are the Step inputs by key. The value of an input comes from the first source it is set in: the flags, the
+// config file, the environment (like when run by Bitrise), then the defaults of the Step.
+type inputs map[string]string
+
+// Getenv returns the value of the input, so that the inputs can be parsed by stepconf.
+func (i inputs) Getenv(key string) string {
+	return i[key]
+}
+
+// inputKeys returns the keys of the Step inputs, as defined by the env tags of the Configs.
+func inputKeys() []string {
+	var keys []string
+	t := reflect.TypeOf(googleplay.Configs{})
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		keys = append(keys, strings.Split(tag, ",")[0])
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// inputFlags registers a flag for every Step input, and the flag of the config file.
+type inputFlags struct {
+	configPath string
+	values     map[string]*string
+}
+
+func registerInputFlags(flags *flag.FlagSet) *inputFlags {
+	f := &inputFlags{values: map[string]*string{}}
+	flags.StringVar(&f.configPath, "config", "", "Path of a YAML file with the Step inputs by key, like package_name: io.bitrise.sample")
+	for _, key := range inputKeys() {
+		f.values[key] = flags.String(key, "", fmt.Sprintf("The %s Step input", key))
+	}
+	return f
+}
+
+// resolve returns the inputs from the flags set on the command line, the config file, the environment and the defaults.
+func (f *inputFlags) resolve(flags *flag.FlagSet, getenv func(string) string) (inputs, error) {
+	resolved := inputs{}
+	for key, value := range defaultInputs {
+		resolved[key] = value
+	}
+	for _, key := range inputKeys() {
+		if value := getenv(key); value != "" {
+			resolved[key] = value
+		}
+	}
+
+	if f.configPath != "" {
+		fromFile, err := readConfigFile(f.configPath)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range fromFile {
+			resolved[key] = value
+		}
+	}
+
+	flags.Visit(func(fl *flag.Flag) {
+		if value, ok := f.values[fl.Name]; ok {
+			resolved[fl.Name] = *value
+		}
+	})
+	return resolved, nil
+}
+
+// readConfigFile reads the Step inputs from a YAML file. Scalar values are used as strings, like in the env inputs.
+func readConfigFile(pth string) (inputs, error) {
+	content, err := os.ReadFile(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file, error: %w", err)
+	}
+
+	var values map[string]yaml.Node
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse config file, error: %w", err)
+	}
+
+	known := map[string]bool{}
+	for _, key := range inputKeys() {
+		known[key] = true
+	}
+	config := inputs{}
+	for key, node := range values {
+		if !known[key] {
+			return nil, fmt.Errorf("unknown input in config file: %s", key)
+		}
+		if node.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("the %s input of the config file must be a string, number or boolean", key)
+		}
+		config[key] = node.Value
+	}
+	return config, nil
+}
+
+// parseConfigs parses the inputs into the configuration of the publisher, with the validations of the Step.
+func parseConfigs(in inputs) (googleplay.Configs, error) {
+	var configs googleplay.Configs
+	if err := stepconf.NewEnvParser(in).Parse(&configs); err != nil {
+		return googleplay.Configs{}, err
+	}
+	return configs, nil
+}
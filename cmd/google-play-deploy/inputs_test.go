@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_defaultInputs(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("..", "..", "step.yml"))
+	require.NoError(t, err)
+	var step struct {
+		Inputs []map[string]interface{} `yaml:"inputs"`
+	}
+	require.NoError(t, yaml.Unmarshal(content, &step))
+
+	stepDefaults := map[string]string{}
+	for _, input := range step.Inputs {
+		for key, value := range input {
+			// The track is not defaulted by the command line tool.
+			if key == "opts" || key == "track" || value == nil {
+				continue
+			}
+			if s := fmt.Sprint(value); s != "" && !strings.HasPrefix(s, "$") {
+				stepDefaults[key] = s
+			}
+		}
+	}
+	assert.Equal(t, stepDefaults, map[string]string(defaultInputs), "defaults.go is out of date, run go generate ./cmd/google-play-deploy")
+}
+
+func TestInputFlags_resolve(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(configPath, []byte("package_name: io.bitrise.config\ntrack: beta\nuser_fraction: 0.5\ndry_run: true\n"), 0600))
+	env := map[string]string{"package_name": "io.bitrise.env", "track": "alpha", "release_name": "1.0", "mode": "upload_only"}
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	inFlags := registerInputFlags(flags)
+	require.NoError(t, flags.Parse([]string{"-config", configPath, "-track", "production"}))
+
+	in, err := inFlags.resolve(flags, func(key string) string { return env[key] })
+	require.NoError(t, err)
+	assert.Equal(t, "production", in["track"], "flags take precedence")
+	assert.Equal(t, "io.bitrise.config", in["package_name"], "config file takes precedence over the environment")
+	assert.Equal(t, "0.5", in["user_fraction"])
+	assert.Equal(t, "1.0", in["release_name"], "environment is used")
+	assert.Equal(t, "upload_only", in["mode"], "environment takes precedence over the defaults")
+	assert.Equal(t, "append", in["testers_update_mode"], "defaults are used")
+
+	in["service_account_json_key_path"] = "file:///key.json"
+	configs, err := parseConfigs(in)
+	require.NoError(t, err)
+	assert.Equal(t, "production", configs.Track)
+	assert.Equal(t, 0.5, configs.UserFraction)
+	assert.True(t, configs.DryRun)
+	assert.Equal(t, "append", configs.TestersUpdateMode)
+}
+
+func Test_readConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    inputs
+		wantErr string
+	}{
+		{name: "scalars", content: "track: beta\nedit_retries: 3\n", want: inputs{"track": "beta", "edit_retries": "3"}},
+		{name: "unknown input", content: "trac: beta\n", wantErr: "unknown input in config file: trac"},
+		{name: "not a scalar", content: "track:\n- beta\n", wantErr: "the track input of the config file must be a string, number or boolean"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pth := filepath.Join(t.TempDir(), "config.yml")
+			require.NoError(t, os.WriteFile(pth, []byte(tt.content), 0600))
+
+			got, err := readConfigFile(pth)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_run(t *testing.T) {
+	simulated := []string{"-package_name", "io.bitrise.sample", "-service_account_json_key_path", "file:///key.json", "-simulate", "true"}
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key.json")
+	require.NoError(t, os.WriteFile(keyPath, []byte("{}"), 0600))
+	appPath := filepath.Join(dir, "app.apk")
+	require.NoError(t, os.WriteFile(appPath, []byte("versionCode=1"), 0600))
+	internalAppSharing := []string{"upload", "-package_name", "io.bitrise.sample", "-service_account_json_key_path", "file://" + keyPath, "-simulate", "true", "-mode", "internal_app_sharing", "-app_path", appPath}
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+	}{
+		{name: "no command", wantCode: 2},
+		{name: "unknown command", args: []string{"publish"}, wantCode: 2},
+		{name: "unknown listings command", args: []string{"listings", "sync"}, wantCode: 2},
+		{name: "missing required input", args: []string{"tracks"}, wantCode: 2},
+		{name: "promote without the source track", args: append([]string{"promote", "-track", "production"}, simulated...), wantCode: 2},
+		{name: "tracks", args: append([]string{"tracks"}, simulated...), wantCode: 0},
+		{name: "tracks without a key in simulate mode", args: []string{"tracks", "-package_name", "io.bitrise.sample", "-simulate", "true"}, wantCode: 0},
+		{name: "halt without a staged rollout", args: append([]string{"halt", "-track", "production"}, simulated...), wantCode: 1},
+		{name: "listings pull", args: append([]string{"listings", "pull", "-dir", t.TempDir()}, simulated...), wantCode: 0},
+		{name: "upload to internal app sharing", args: internalAppSharing, wantCode: 0},
+		{name: "upload to internal app sharing printing the outputs", args: append(internalAppSharing, "-print_outputs"), wantCode: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := run(tt.args, func(string) string { return "" }, io.Discard)
+			assert.Equal(t, tt.wantCode, code)
+		})
+	}
+}
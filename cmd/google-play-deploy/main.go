@@ -0,0 +1,281 @@
+// Command google-play-deploy runs the publishing logic of the Google Play Deploy Step from the command line, to release
+// and manage the releases of an app locally, for example when debugging a failed release.
+//
+// Usage:
+//
+//	google-play-deploy <command> [flags]
+//
+// The flags of every command are the Step inputs by key (like -package_name and -track), and -config with the path of a
+// YAML file of the inputs. An input is read from the flags first, then from the config file, then from the environment
+// like when the Step runs on Bitrise, then the default of the Step is used.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-google-play-deploy/googleplay"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+// commandOptions are the flags of the commands besides the Step inputs.
+type commandOptions struct {
+	from string
+	dir  string
+	// printOutputs prints the outputs the Step would export, instead of discarding them.
+	printOutputs bool
+}
+
+type command struct {
+	summary string
+	// fromFlag and dirFlag register the -from and -dir flags of the command.
+	fromFlag bool
+	dirFlag  bool
+	// validate checks the configuration before the service is created.
+	validate func(configs *googleplay.Configs, opts commandOptions) error
+	run      func(ctx context.Context, p *googleplay.Publisher, service *androidpublisher.Service, configs googleplay.Configs, opts commandOptions) error
+}
+
+var commands = map[string]command{
+	"upload": {
+		summary: "Upload the apps and release them to the track, or run the configured mode of the Step",
+		validate: func(configs *googleplay.Configs, _ commandOptions) error {
+			return configs.Validate()
+		},
+		run: func(ctx context.Context, p *googleplay.Publisher, service *androidpublisher.Service, configs googleplay.Configs, _ commandOptions) error {
+			_, err := p.Deploy(ctx, service, configs)
+			return err
+		},
+	},
+	"validate": {
+		summary: "Upload the apps and update the track in an edit, then validate the edit without committing it",
+		validate: func(configs *googleplay.Configs, _ commandOptions) error {
+			configs.DryRun = true
+			return configs.Validate()
+		},
+		run: func(ctx context.Context, p *googleplay.Publisher, service *androidpublisher.Service, configs googleplay.Configs, _ commandOptions) error {
+			_, err := p.Deploy(ctx, service, configs)
+			return err
+		},
+	},
+	"promote": {
+		summary:  "Release the latest release of the -from track to the track",
+		fromFlag: true,
+		validate: func(configs *googleplay.Configs, opts commandOptions) error {
+			if opts.from == "" {
+				return errors.New("the track to promote from (-from) is required")
+			}
+			return requireTrack(configs, opts)
+		},
+		run: func(ctx context.Context, p *googleplay.Publisher, service *androidpublisher.Service, configs googleplay.Configs, opts commandOptions) error {
+			release, err := p.Promote(ctx, service, configs, opts.from)
+			if err != nil {
+				return err
+			}
+			printRelease(configs.Track, release)
+			return nil
+		},
+	},
+	"rollout": {
+		summary:  "Update the user fraction (-user_fraction) of the staged rollout of the track, or complete it (-status completed)",
+		validate: requireTrack,
+		run: func(ctx context.Context, p *googleplay.Publisher, service *androidpublisher.Service, configs googleplay.Configs, _ commandOptions) error {
+			release, err := p.Rollout(ctx, service, configs)
+			if err != nil {
+				return err
+			}
+			printRelease(configs.Track, release)
+			return nil
+		},
+	},
+	"halt": {
+		summary:  "Halt the staged rollout of the track",
+		validate: requireTrack,
+		run: func(ctx context.Context, p *googleplay.Publisher, service *androidpublisher.Service, configs googleplay.Configs, _ commandOptions) error {
+			release, err := p.Halt(ctx, service, configs)
+			if err != nil {
+				return err
+			}
+			printRelease(configs.Track, release)
+			return nil
+		},
+	},
+	"tracks": {
+		summary: "List the tracks of the app with their releases",
+		run: func(ctx context.Context, p *googleplay.Publisher, service *androidpublisher.Service, configs googleplay.Configs, _ commandOptions) error {
+			tracks, err := p.Tracks(ctx, service, configs)
+			if err != nil {
+				return err
+			}
+			return googleplay.WriteTracksTable(os.Stdout, tracks)
+		},
+	},
+	"listings pull": {
+		summary: "Write the store listings of the app into the -dir directory, in a directory per language",
+		dirFlag: true,
+		validate: func(_ *googleplay.Configs, opts commandOptions) error {
+			return requireDir(opts)
+		},
+		run: func(ctx context.Context, p *googleplay.Publisher, service *androidpublisher.Service, configs googleplay.Configs, opts commandOptions) error {
+			languages, err := p.PullListings(ctx, service, configs, opts.dir)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Pulled listings: %s\n", strings.Join(languages, ", "))
+			return nil
+		},
+	},
+	"listings push": {
+		summary: "Update the store listings of the app from the directories per language of the -dir directory",
+		dirFlag: true,
+		validate: func(_ *googleplay.Configs, opts commandOptions) error {
+			return requireDir(opts)
+		},
+		run: func(ctx context.Context, p *googleplay.Publisher, service *androidpublisher.Service, configs googleplay.Configs, opts commandOptions) error {
+			languages, err := p.PushListings(ctx, service, configs, opts.dir)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Pushed listings: %s\n", strings.Join(languages, ", "))
+			return nil
+		},
+	},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Getenv, os.Stderr))
+}
+
+// run runs the command of the given arguments. Returns the exit code: 0 on success, 1 if the command failed and 2 if
+// the arguments are invalid.
+func run(args []string, getenv func(string) string, output io.Writer) int {
+	name, args := commandName(args)
+	cmd, ok := commands[name]
+	if !ok {
+		if name != "" && name != "help" && name != "-h" && name != "-help" && name != "--help" {
+			fmt.Fprintf(output, "Unknown command: %s\n\n", name)
+		}
+		printUsage(output)
+		return 2
+	}
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(output)
+	inFlags := registerInputFlags(flags)
+	var opts commandOptions
+	if cmd.fromFlag {
+		flags.StringVar(&opts.from, "from", "", "The track to promote the release from")
+	}
+	if cmd.dirFlag {
+		flags.StringVar(&opts.dir, "dir", "", "The listings directory, with a directory per language like en-US/title.txt")
+	}
+	flags.BoolVar(&opts.printOutputs, "print_outputs", false, "Print the outputs the Step would export, as KEY=value lines")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	in, err := inFlags.resolve(flags, getenv)
+	if err != nil {
+		fmt.Fprintf(output, "%s\n", err)
+		return 2
+	}
+	configs, err := parseConfigs(in)
+	if err != nil {
+		fmt.Fprintf(output, "Invalid inputs: %s\n", err)
+		return 2
+	}
+
+	logger := log.NewLogger(log.WithDebugLog(configs.IsDebugLog))
+	configs.Logger = logger
+	if cmd.validate != nil {
+		if err := cmd.validate(&configs, opts); err != nil {
+			logger.Errorf("%s", err)
+			return 2
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	var outputs googleplay.OutputSink
+	if opts.printOutputs {
+		outputs = printOutput
+	}
+	publisher := googleplay.New(googleplay.Options{Logger: logger, Outputs: outputs})
+	service, err := publisher.NewService(ctx, configs)
+	if err == nil {
+		err = cmd.run(ctx, publisher, service, configs, opts)
+	}
+	if err != nil {
+		publisher.ReportFailure(err)
+		return 1
+	}
+	return 0
+}
+
+// printOutput prints an output of the command as a KEY=value line.
+func printOutput(key, value string) error {
+	_, err := fmt.Printf("%s=%s\n", key, value)
+	return err
+}
+
+// commandName returns the name of the command of the arguments, including the subcommand of listings, and the rest
+// of the arguments.
+func commandName(args []string) (string, []string) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	if args[0] == "listings" && len(args) > 1 {
+		return args[0] + " " + args[1], args[2:]
+	}
+	return args[0], args[1:]
+}
+
+func printUsage(output io.Writer) {
+	fmt.Fprintf(output, "Usage: google-play-deploy <command> [flags]\n\nCommands:\n")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(output, "  %-15s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintf(output, "\nThe flags of the commands are the Step inputs by key, like -package_name and -track, and -config with\n"+
+		"the path of a YAML file of the inputs. Run google-play-deploy <command> -h to list them.\n")
+}
+
+func requireTrack(configs *googleplay.Configs, _ commandOptions) error {
+	if configs.Track == "" {
+		return errors.New("track is required")
+	}
+	return nil
+}
+
+func requireDir(opts commandOptions) error {
+	if opts.dir == "" {
+		return errors.New("the listings directory (-dir) is required")
+	}
+	return nil
+}
+
+func printRelease(track string, release *androidpublisher.TrackRelease) {
+	fmt.Printf("%s: %s\n", track, formatRelease(release))
+}
+
+func formatRelease(release *androidpublisher.TrackRelease) string {
+	s := fmt.Sprintf("%s (status: %s, version codes: %v", release.Name, release.Status, release.VersionCodes)
+	if release.UserFraction != 0 {
+		s += fmt.Sprintf(", user fraction: %v", release.UserFraction)
+	}
+	return s + ")"
+}
@@ -0,0 +1,141 @@
+package emulator
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+// signingKeyHash is the SHA-256 hash of the certificate the emulator signs the generated APKs with.
+const signingKeyHash = "a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90"
+
+// media is the content of a downloaded file, written as is instead of as JSON.
+type media []byte
+
+// committedBundle returns the app of the given package, failing if no bundle with the given version code was committed.
+func (s *Server) committedBundle(packageName, versionCode string) (*app, int64, error) {
+	a, err := s.app(packageName)
+	if err != nil {
+		return nil, 0, err
+	}
+	code, err := strconv.ParseInt(versionCode, 10, 64)
+	if err != nil {
+		return nil, 0, badRequest("badRequest", "Invalid version code: %s.", versionCode)
+	}
+	for _, bundle := range a.committed.Bundles {
+		if bundle.VersionCode == code {
+			return a, code, nil
+		}
+	}
+	return nil, 0, notFound("notFound", "No app bundle found with version code %d.", code)
+}
+
+// generatedApks returns the APKs generated from the bundle of the given version code: a universal APK and the splits
+// of the base module, signed with a single key.
+func generatedApks(versionCode int64) *androidpublisher.GeneratedApksPerSigningKey {
+	return &androidpublisher.GeneratedApksPerSigningKey{
+		CertificateSha256Hash: signingKeyHash,
+		GeneratedUniversalApk: &androidpublisher.GeneratedUniversalApk{DownloadId: fmt.Sprintf("universal-%d", versionCode)},
+		GeneratedSplitApks: []*androidpublisher.GeneratedSplitApk{
+			{DownloadId: fmt.Sprintf("split-%d-master", versionCode), ModuleName: "base", VariantId: 1},
+			{DownloadId: fmt.Sprintf("split-%d-arm64", versionCode), ModuleName: "base", SplitId: "config.arm64_v8a", VariantId: 1},
+		},
+	}
+}
+
+func (s *Server) listGeneratedApks(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, versionCode, err := s.committedBundle(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	return &androidpublisher.GeneratedApksListResponse{
+		GeneratedApks: []*androidpublisher.GeneratedApksPerSigningKey{generatedApks(versionCode)},
+	}, nil
+}
+
+func (s *Server) downloadGeneratedApk(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, versionCode, err := s.committedBundle(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	apks := generatedApks(versionCode)
+	downloadIDs := []string{apks.GeneratedUniversalApk.DownloadId}
+	for _, split := range apks.GeneratedSplitApks {
+		downloadIDs = append(downloadIDs, split.DownloadId)
+	}
+	for _, downloadID := range downloadIDs {
+		if downloadID == params[2] {
+			return media(GeneratedApk(params[2])), nil
+		}
+	}
+	return nil, notFound("notFound", "Generated APK not found: %s.", params[2])
+}
+
+// GeneratedApk returns the content of the generated APK with the given download ID, as downloaded from the emulator.
+func GeneratedApk(downloadID string) []byte {
+	return []byte("generated APK " + downloadID)
+}
+
+func (s *Server) listSystemApkVariants(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, versionCode, err := s.committedBundle(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	return &androidpublisher.SystemApksListResponse{Variants: a.systemApkVariants[versionCode]}, nil
+}
+
+func (s *Server) createSystemApkVariant(req *http.Request, params []string) (interface{}, error) {
+	var variant androidpublisher.Variant
+	if err := decodeBody(req, &variant); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, versionCode, err := s.committedBundle(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	if variant.DeviceSpec == nil {
+		return nil, badRequest("badRequest", "Device spec is required.")
+	}
+	if a.systemApkVariants == nil {
+		a.systemApkVariants = map[int64][]*androidpublisher.Variant{}
+	}
+	variant.VariantId = int64(len(a.systemApkVariants[versionCode]) + 1)
+	a.systemApkVariants[versionCode] = append(a.systemApkVariants[versionCode], &variant)
+	return &variant, nil
+}
+
+func (s *Server) downloadSystemApk(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, versionCode, err := s.committedBundle(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	for _, variant := range a.systemApkVariants[versionCode] {
+		if strconv.FormatInt(variant.VariantId, 10) == params[2] {
+			return media(SystemApk(versionCode, variant.VariantId)), nil
+		}
+	}
+	return nil, notFound("notFound", "System APK variant not found: %s.", params[2])
+}
+
+// SystemApk returns the content of the system APK of the given variant, as downloaded from the emulator.
+func SystemApk(versionCode, variantID int64) []byte {
+	return []byte(fmt.Sprintf("system APK %d-%d", versionCode, variantID))
+}
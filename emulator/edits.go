@@ -0,0 +1,467 @@
+package emulator
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+const editLifetime = time.Hour
+
+// appState is the state of an app an edit can change.
+type appState struct {
+	Tracks              map[string]*androidpublisher.Track
+	Listings            map[string]*androidpublisher.Listing
+	Testers             map[string]*androidpublisher.Testers
+	CountryAvailability map[string]*androidpublisher.TrackCountryAvailability
+	Details             *androidpublisher.AppDetails
+	Bundles             []*androidpublisher.Bundle
+	Apks                []*androidpublisher.Apk
+	// BundleDeviceTierConfigs are the IDs of the device tier configs the bundles were uploaded with, by version code.
+	BundleDeviceTierConfigs map[int64]int64
+}
+
+type app struct {
+	packageName                    string
+	committed                      appState
+	revision                       int
+	requireChangesNotSentForReview bool
+	deviceTierConfigs              []*androidpublisher.DeviceTierConfig
+	// systemApkVariants are the system APK variants by the version code of their bundle.
+	systemApkVariants map[int64][]*androidpublisher.Variant
+}
+
+type edit struct {
+	id           string
+	packageName  string
+	state        appState
+	baseRevision int
+	expiry       time.Time
+}
+
+func (s *Server) newID() string {
+	id := s.nextID
+	s.nextID++
+	return strconv.FormatInt(id, 10)
+}
+
+func (s *Server) app(packageName string) (*app, error) {
+	a, ok := s.apps[packageName]
+	if !ok {
+		return nil, notFound("applicationNotFound", "Package not found: %s.", packageName)
+	}
+	return a, nil
+}
+
+// edit returns the open edit with the given ID, failing if an other edit of the app was committed since it was
+// created, like Google Play does.
+func (s *Server) edit(packageName, editID string) (*app, *edit, error) {
+	a, err := s.app(packageName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	e, ok := s.edits[editID]
+	if !ok || e.packageName != packageName {
+		return nil, nil, badRequest("editDeleted", EditDeletedMessage)
+	}
+	if e.baseRevision != a.revision || time.Now().After(e.expiry) {
+		delete(s.edits, editID)
+		return nil, nil, badRequest("editDeleted", EditDeletedMessage)
+	}
+	return a, e, nil
+}
+
+func (e *edit) resource() *androidpublisher.AppEdit {
+	return &androidpublisher.AppEdit{Id: e.id, ExpiryTimeSeconds: strconv.FormatInt(e.expiry.Unix(), 10)}
+}
+
+func (s *Server) insertEdit(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, err := s.app(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	e := &edit{
+		id:           s.newID(),
+		packageName:  a.packageName,
+		baseRevision: a.revision,
+		expiry:       time.Now().Add(editLifetime),
+	}
+	clone(a.committed, &e.state)
+	s.edits[e.id] = e
+	return e.resource(), nil
+}
+
+func (s *Server) getEdit(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	return e.resource(), nil
+}
+
+func (s *Server) deleteEdit(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	delete(s.edits, e.id)
+	return nil, nil
+}
+
+func (s *Server) validateEdit(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	return e.resource(), nil
+}
+
+func (s *Server) commitEdit(req *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if a.requireChangesNotSentForReview && req.URL.Query().Get("changesNotSentForReview") != "true" {
+		return nil, badRequest("badRequest", ChangesNotSentForReviewMessage)
+	}
+
+	a.committed = e.state
+	a.revision++
+	delete(s.edits, e.id)
+	return e.resource(), nil
+}
+
+func (s *Server) listTracks(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &androidpublisher.TracksListResponse{Kind: "androidpublisher#tracksListResponse"}
+	for _, name := range sortedKeys(e.state.Tracks) {
+		resp.Tracks = append(resp.Tracks, e.state.Tracks[name])
+	}
+	return resp, nil
+}
+
+func (s *Server) getTrack(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	track, ok := e.state.Tracks[params[2]]
+	if !ok {
+		return nil, notFound("notFound", "Track not found: %s.", params[2])
+	}
+	return track, nil
+}
+
+// updateTrack replaces the releases of the track. Like on Google Play, a completed release is kept when only a
+// staged rollout is specified.
+func (s *Server) updateTrack(req *http.Request, params []string) (interface{}, error) {
+	var track androidpublisher.Track
+	if err := decodeBody(req, &track); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	current, ok := e.state.Tracks[params[2]]
+	if !ok {
+		return nil, notFound("notFound", "Track not found: %s.", params[2])
+	}
+	if err := e.validateReleases(track.Releases); err != nil {
+		return nil, err
+	}
+
+	releases := track.Releases
+	if !hasRelease(releases, "completed") && hasRelease(releases, "inProgress") {
+		for _, release := range current.Releases {
+			if release.Status == "completed" {
+				releases = append([]*androidpublisher.TrackRelease{release}, releases...)
+				break
+			}
+		}
+	}
+
+	updated := &androidpublisher.Track{Track: params[2], Releases: releases}
+	e.state.Tracks[params[2]] = updated
+	return updated, nil
+}
+
+func (e *edit) validateReleases(releases []*androidpublisher.TrackRelease) error {
+	completed := 0
+	for _, release := range releases {
+		switch release.Status {
+		case "completed":
+			completed++
+			if release.UserFraction != 0 {
+				return badRequest("releaseValidationErrorKeyUserFractionNotAllowed", "Release with status completed cannot have a user fraction.")
+			}
+		case "inProgress", "halted":
+			if release.UserFraction <= 0 || release.UserFraction >= 1 {
+				return badRequest("releaseValidationErrorKeyUserFractionRequired", "Release with status %s must have a user fraction in (0, 1).", release.Status)
+			}
+		case "draft":
+			if release.UserFraction != 0 {
+				return badRequest("releaseValidationErrorKeyUserFractionNotAllowed", "Release with status draft cannot have a user fraction.")
+			}
+		default:
+			return badRequest("badRequest", "Invalid value for release status: %s.", release.Status)
+		}
+
+		for _, versionCode := range release.VersionCodes {
+			if !e.hasVersionCode(versionCode) {
+				return forbidden("apkNotFound", "Version code %d could not be found.", versionCode)
+			}
+		}
+	}
+
+	if completed > 1 {
+		return badRequest("releasesTooManyCompletedReleases", TooManyCompletedReleasesMessage)
+	}
+	return nil
+}
+
+func (e *edit) hasVersionCode(versionCode int64) bool {
+	for _, bundle := range e.state.Bundles {
+		if bundle.VersionCode == versionCode {
+			return true
+		}
+	}
+	for _, apk := range e.state.Apks {
+		if apk.VersionCode == versionCode {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRelease(releases []*androidpublisher.TrackRelease, status string) bool {
+	for _, release := range releases {
+		if release.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) listListings(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &androidpublisher.ListingsListResponse{Kind: "androidpublisher#listingsListResponse"}
+	for _, language := range sortedKeys(e.state.Listings) {
+		resp.Listings = append(resp.Listings, e.state.Listings[language])
+	}
+	return resp, nil
+}
+
+func (s *Server) getListing(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	listing, ok := e.state.Listings[params[2]]
+	if !ok {
+		return nil, notFound("notFound", "Listing not found for language: %s.", params[2])
+	}
+	return listing, nil
+}
+
+func (s *Server) updateListing(req *http.Request, params []string) (interface{}, error) {
+	var listing androidpublisher.Listing
+	if err := decodeBody(req, &listing); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	listing.Language = params[2]
+	e.state.Listings[params[2]] = &listing
+	return &listing, nil
+}
+
+func (s *Server) getTesters(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := e.state.Tracks[params[2]]; !ok {
+		return nil, notFound("notFound", "Track not found: %s.", params[2])
+	}
+	if testers, ok := e.state.Testers[params[2]]; ok {
+		return testers, nil
+	}
+	return &androidpublisher.Testers{}, nil
+}
+
+func (s *Server) updateTesters(req *http.Request, params []string) (interface{}, error) {
+	var testers androidpublisher.Testers
+	if err := decodeBody(req, &testers); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	if params[2] == "production" {
+		return nil, badRequest("badRequest", "Testers cannot be set on the production track.")
+	}
+	if _, ok := e.state.Tracks[params[2]]; !ok {
+		return nil, notFound("notFound", "Track not found: %s.", params[2])
+	}
+	e.state.Testers[params[2]] = &testers
+	return &testers, nil
+}
+
+func (s *Server) getDetails(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	return e.state.Details, nil
+}
+
+func (s *Server) getCountryAvailability(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := e.state.Tracks[params[2]]; !ok {
+		return nil, notFound("notFound", "Track not found: %s.", params[2])
+	}
+	if availability, ok := e.state.CountryAvailability[params[2]]; ok {
+		return availability, nil
+	}
+	return &androidpublisher.TrackCountryAvailability{RestOfWorld: true}, nil
+}
+
+func (s *Server) listBundles(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	return &androidpublisher.BundlesListResponse{Kind: "androidpublisher#bundlesListResponse", Bundles: e.state.Bundles}, nil
+}
+
+func (s *Server) listApks(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	return &androidpublisher.ApksListResponse{Kind: "androidpublisher#apksListResponse", Apks: e.state.Apks}, nil
+}
+
+func (s *Server) listDeviceTierConfigs(_ *http.Request, params []string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, err := s.app(params[0])
+	if err != nil {
+		return nil, err
+	}
+	return &androidpublisher.ListDeviceTierConfigsResponse{DeviceTierConfigs: a.deviceTierConfigs}, nil
+}
+
+func (s *Server) createDeviceTierConfig(req *http.Request, params []string) (interface{}, error) {
+	var config androidpublisher.DeviceTierConfig
+	if err := decodeBody(req, &config); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, err := s.app(params[0])
+	if err != nil {
+		return nil, err
+	}
+	config.DeviceTierConfigId = s.nextID
+	s.nextID++
+	a.deviceTierConfigs = append(a.deviceTierConfigs, &config)
+	return &config, nil
+}
+
+// deviceTierConfig returns the device tier config of the app with the given ID, or nil if there is none.
+func (a *app) deviceTierConfig(id string) *androidpublisher.DeviceTierConfig {
+	for _, config := range a.deviceTierConfigs {
+		if strconv.FormatInt(config.DeviceTierConfigId, 10) == id {
+			return config
+		}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,351 @@
+// Package emulator implements an in-memory Google Play Developer API server, covering the edits, uploads, tracks,
+// listings, testers, internal app sharing, generated APKs and system APKs endpoints the step uses. It is used to
+// rehearse deployments without touching a real app, and to test the step end to end.
+package emulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+// BuiltInTracks are the tracks every app has on Google Play.
+var BuiltInTracks = []string{"production", "beta", "alpha", "internal"}
+
+// Server is an in-memory Google Play Developer API. It can be used as an http.Handler, or as the
+// http.RoundTripper of the client the API service is created with (see Client).
+type Server struct {
+	mu       sync.Mutex
+	apps     map[string]*app
+	edits    map[string]*edit
+	sessions map[string]*uploadSession
+	nextID   int64
+}
+
+// NewServer creates an emulator without any app.
+func NewServer() *Server {
+	return &Server{
+		apps:     map[string]*app{},
+		edits:    map[string]*edit{},
+		sessions: map[string]*uploadSession{},
+		nextID:   1,
+	}
+}
+
+// AppOption configures an app added to the emulator.
+type AppOption func(*app)
+
+// WithTracks adds empty tracks to the app, besides the built-in ones.
+func WithTracks(tracks ...string) AppOption {
+	return func(a *app) {
+		for _, track := range tracks {
+			if _, ok := a.committed.Tracks[track]; !ok {
+				a.committed.Tracks[track] = &androidpublisher.Track{Track: track}
+			}
+		}
+	}
+}
+
+// WithRelease adds an already published release to the given track of the app, marking its version codes as used.
+func WithRelease(track string, release androidpublisher.TrackRelease) AppOption {
+	return func(a *app) {
+		WithTracks(track)(a)
+		a.committed.Tracks[track].Releases = append(a.committed.Tracks[track].Releases, &release)
+		for _, versionCode := range release.VersionCodes {
+			a.committed.Bundles = append(a.committed.Bundles, &androidpublisher.Bundle{VersionCode: versionCode})
+		}
+	}
+}
+
+// WithListing adds a store listing to the app.
+func WithListing(listing androidpublisher.Listing) AppOption {
+	return func(a *app) {
+		a.committed.Listings[listing.Language] = &listing
+	}
+}
+
+// WithTesters sets the Google Groups testers of the given track of the app.
+func WithTesters(track string, googleGroups ...string) AppOption {
+	return func(a *app) {
+		a.committed.Testers[track] = &androidpublisher.Testers{GoogleGroups: googleGroups}
+	}
+}
+
+// WithCountryAvailability sets the countries the given track of the app is available in.
+func WithCountryAvailability(track string, availability androidpublisher.TrackCountryAvailability) AppOption {
+	return func(a *app) {
+		a.committed.CountryAvailability[track] = &availability
+	}
+}
+
+// WithDeviceTierConfig adds an existing device tier config to the app, with the ID it has.
+func WithDeviceTierConfig(config androidpublisher.DeviceTierConfig) AppOption {
+	return func(a *app) {
+		a.deviceTierConfigs = append(a.deviceTierConfigs, &config)
+	}
+}
+
+// RequireChangesNotSentForReview makes committing an edit of the app fail unless changesNotSentForReview is set, like
+// for apps with rejected updates.
+func RequireChangesNotSentForReview() AppOption {
+	return func(a *app) {
+		a.requireChangesNotSentForReview = true
+	}
+}
+
+// AddApp adds an app with the built-in tracks to the emulator.
+func (s *Server) AddApp(packageName string, opts ...AppOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := &app{
+		packageName: packageName,
+		committed: appState{
+			Tracks:              map[string]*androidpublisher.Track{},
+			Listings:            map[string]*androidpublisher.Listing{},
+			Testers:             map[string]*androidpublisher.Testers{},
+			CountryAvailability: map[string]*androidpublisher.TrackCountryAvailability{},
+			Details:             &androidpublisher.AppDetails{DefaultLanguage: "en-US"},
+		},
+	}
+	WithTracks(BuiltInTracks...)(a)
+	for _, opt := range opts {
+		opt(a)
+	}
+	s.apps[packageName] = a
+}
+
+// Track returns the committed state of the given track, or nil if the app or the track does not exist.
+func (s *Server) Track(packageName, track string) *androidpublisher.Track {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.apps[packageName]
+	if !ok || a.committed.Tracks[track] == nil {
+		return nil
+	}
+	var committed androidpublisher.Track
+	clone(a.committed.Tracks[track], &committed)
+	return &committed
+}
+
+// Listing returns the committed store listing of the given language, or nil if the app or the listing does not exist.
+func (s *Server) Listing(packageName, language string) *androidpublisher.Listing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.apps[packageName]
+	if !ok || a.committed.Listings[language] == nil {
+		return nil
+	}
+	var committed androidpublisher.Listing
+	clone(a.committed.Listings[language], &committed)
+	return &committed
+}
+
+// Testers returns the committed testers of the given track, or nil if the app or the testers do not exist.
+func (s *Server) Testers(packageName, track string) *androidpublisher.Testers {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.apps[packageName]
+	if !ok || a.committed.Testers[track] == nil {
+		return nil
+	}
+	var committed androidpublisher.Testers
+	clone(a.committed.Testers[track], &committed)
+	return &committed
+}
+
+// DeviceTierConfigs returns the device tier configs of the app.
+func (s *Server) DeviceTierConfigs(packageName string) []*androidpublisher.DeviceTierConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.apps[packageName]
+	if !ok {
+		return nil
+	}
+	var configs []*androidpublisher.DeviceTierConfig
+	clone(a.deviceTierConfigs, &configs)
+	return configs
+}
+
+// BundleDeviceTierConfig returns the ID of the device tier config the committed bundle of the given version code was
+// uploaded with, or 0 if it was uploaded without one.
+func (s *Server) BundleDeviceTierConfig(packageName string, versionCode int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a, ok := s.apps[packageName]; ok {
+		return a.committed.BundleDeviceTierConfigs[versionCode]
+	}
+	return 0
+}
+
+// OpenEdits returns the number of edits of the app which are neither committed nor deleted.
+func (s *Server) OpenEdits(packageName string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, e := range s.edits {
+		if e.packageName == packageName {
+			count++
+		}
+	}
+	return count
+}
+
+// Commits returns the number of edits committed for the app.
+func (s *Server) Commits(packageName string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a, ok := s.apps[packageName]; ok {
+		return a.revision
+	}
+	return 0
+}
+
+// Client returns an HTTP client sending every request to the emulator, regardless of the host.
+func (s *Server) Client() *http.Client {
+	return &http.Client{Transport: s}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (s *Server) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	resp := recorder.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+type route struct {
+	method  string
+	pattern *regexp.Regexp
+	handle  func(s *Server, req *http.Request, params []string) (interface{}, error)
+}
+
+const (
+	apiPrefix    = "^/androidpublisher/v3/applications/"
+	uploadPrefix = "^/(?:resumable/)?upload/androidpublisher/v3/applications/"
+	editPath     = "([^/]+)/edits/([^/:]+)"
+)
+
+var routes = []route{
+	{http.MethodPost, regexp.MustCompile(apiPrefix + "([^/]+)/edits$"), (*Server).insertEdit},
+	{http.MethodGet, regexp.MustCompile(apiPrefix + editPath + "$"), (*Server).getEdit},
+	{http.MethodDelete, regexp.MustCompile(apiPrefix + editPath + "$"), (*Server).deleteEdit},
+	{http.MethodPost, regexp.MustCompile(apiPrefix + editPath + ":validate$"), (*Server).validateEdit},
+	{http.MethodPost, regexp.MustCompile(apiPrefix + editPath + ":commit$"), (*Server).commitEdit},
+	{http.MethodGet, regexp.MustCompile(apiPrefix + editPath + "/tracks$"), (*Server).listTracks},
+	{http.MethodGet, regexp.MustCompile(apiPrefix + editPath + "/tracks/([^/]+)$"), (*Server).getTrack},
+	{http.MethodPut, regexp.MustCompile(apiPrefix + editPath + "/tracks/([^/]+)$"), (*Server).updateTrack},
+	{http.MethodPatch, regexp.MustCompile(apiPrefix + editPath + "/tracks/([^/]+)$"), (*Server).updateTrack},
+	{http.MethodGet, regexp.MustCompile(apiPrefix + editPath + "/listings$"), (*Server).listListings},
+	{http.MethodGet, regexp.MustCompile(apiPrefix + editPath + "/listings/([^/]+)$"), (*Server).getListing},
+	{http.MethodPut, regexp.MustCompile(apiPrefix + editPath + "/listings/([^/]+)$"), (*Server).updateListing},
+	{http.MethodGet, regexp.MustCompile(apiPrefix + editPath + "/testers/([^/]+)$"), (*Server).getTesters},
+	{http.MethodPut, regexp.MustCompile(apiPrefix + editPath + "/testers/([^/]+)$"), (*Server).updateTesters},
+	{http.MethodGet, regexp.MustCompile(apiPrefix + editPath + "/details$"), (*Server).getDetails},
+	{http.MethodGet, regexp.MustCompile(apiPrefix + editPath + "/countryAvailability/([^/]+)$"), (*Server).getCountryAvailability},
+	{http.MethodGet, regexp.MustCompile(apiPrefix + editPath + "/bundles$"), (*Server).listBundles},
+	{http.MethodGet, regexp.MustCompile(apiPrefix + editPath + "/apks$"), (*Server).listApks},
+	{http.MethodGet, regexp.MustCompile(apiPrefix + "([^/]+)/deviceTierConfigs$"), (*Server).listDeviceTierConfigs},
+	{http.MethodPost, regexp.MustCompile(apiPrefix + "([^/]+)/deviceTierConfigs$"), (*Server).createDeviceTierConfig},
+	{http.MethodGet, regexp.MustCompile(apiPrefix + "([^/]+)/generatedApks/([^/]+)$"), (*Server).listGeneratedApks},
+	{http.MethodGet, regexp.MustCompile(apiPrefix + "([^/]+)/generatedApks/([^/]+)/downloads/([^/]+):download$"), (*Server).downloadGeneratedApk},
+	{http.MethodGet, regexp.MustCompile(apiPrefix + "([^/]+)/systemApks/([^/]+)/variants$"), (*Server).listSystemApkVariants},
+	{http.MethodPost, regexp.MustCompile(apiPrefix + "([^/]+)/systemApks/([^/]+)/variants$"), (*Server).createSystemApkVariant},
+	{http.MethodGet, regexp.MustCompile(apiPrefix + "([^/]+)/systemApks/([^/]+)/variants/([^/]+):download$"), (*Server).downloadSystemApk},
+	{http.MethodPost, regexp.MustCompile(uploadPrefix + editPath + "/bundles$"), upload((*Server).uploadBundle)},
+	{http.MethodPost, regexp.MustCompile(uploadPrefix + editPath + "/apks$"), upload((*Server).uploadApk)},
+	{http.MethodPost, regexp.MustCompile(uploadPrefix + editPath + "/apks/([0-9]+)/deobfuscationFiles/([^/]+)$"), upload((*Server).uploadDeobfuscationFile)},
+	{http.MethodPost, regexp.MustCompile(uploadPrefix + editPath + "/apks/([0-9]+)/expansionFiles/([^/]+)$"), upload((*Server).uploadExpansionFile)},
+	{http.MethodPost, regexp.MustCompile(uploadPrefix + "internalappsharing/([^/]+)/artifacts/(bundle|apk)$"), upload((*Server).uploadInternalAppSharingArtifact)},
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	for _, r := range routes {
+		params := r.pattern.FindStringSubmatch(req.URL.Path)
+		if params == nil || r.method != req.Method {
+			continue
+		}
+
+		resource, err := r.handle(s, req, params[1:])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeResource(w, resource)
+		return
+	}
+
+	apiError{
+		Code:    http.StatusNotImplemented,
+		Message: fmt.Sprintf("%s %s is not supported by the emulator.", req.Method, req.URL.Path),
+		Reason:  "notImplemented",
+	}.write(w)
+}
+
+func writeResource(w http.ResponseWriter, resource interface{}) {
+	if incomplete, ok := resource.(uploadIncomplete); ok {
+		// The response of a partial resumable upload, as sent to requests with the X-GUploader-No-308 header.
+		w.Header().Set("X-Http-Status-Code-Override", "308")
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", incomplete.received-1))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if session, ok := resource.(uploadSessionStarted); ok {
+		w.Header().Set("Location", session.location)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if content, ok := resource.(media); ok {
+		w.Header().Set("Content-Type", "application/vnd.android.package-archive")
+		_, _ = w.Write(content)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if resource == nil {
+		resource = struct{}{}
+	}
+	_ = json.NewEncoder(w).Encode(resource)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if apiErr, ok := err.(apiError); ok {
+		apiErr.write(w)
+		return
+	}
+	apiError{Code: http.StatusInternalServerError, Message: "Internal error encountered.", Reason: "backendError"}.write(w)
+}
+
+// clone deep copies src into dst through their JSON representation.
+func clone(src, dst interface{}) {
+	content, err := json.Marshal(src)
+	if err != nil {
+		panic(fmt.Sprintf("failed to clone %T: %s", src, err))
+	}
+	if err := json.Unmarshal(content, dst); err != nil {
+		panic(fmt.Sprintf("failed to clone %T: %s", src, err))
+	}
+}
+
+func decodeBody(req *http.Request, v interface{}) error {
+	if err := json.NewDecoder(req.Body).Decode(v); err != nil {
+		return badRequest("badRequest", "Invalid JSON payload received. %s", strings.TrimSpace(err.Error()))
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package emulator
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/androidpublisher/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+const packageName = "io.bitrise.sample"
+
+func newService(t *testing.T, server *Server) *androidpublisher.Service {
+	service, err := androidpublisher.NewService(context.Background(), option.WithHTTPClient(server.Client()))
+	require.NoError(t, err)
+	return service
+}
+
+func uploadBundle(service *androidpublisher.Service, editID string, content []byte, opts ...googleapi.MediaOption) (*androidpublisher.Bundle, error) {
+	opts = append([]googleapi.MediaOption{googleapi.ContentType("application/octet-stream")}, opts...)
+	return service.Edits.Bundles.Upload(packageName, editID).Media(bytes.NewReader(content), opts...).Do()
+}
+
+func TestServer_deploy(t *testing.T) {
+	server := NewServer()
+	server.AddApp(packageName, WithRelease("production", androidpublisher.TrackRelease{Status: "completed", VersionCodes: []int64{1}}))
+	service := newService(t, server)
+
+	edit, err := service.Edits.Insert(packageName, &androidpublisher.AppEdit{}).Do()
+	require.NoError(t, err)
+
+	bundle, err := uploadBundle(service, edit.Id, []byte("versionCode=2"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), bundle.VersionCode)
+
+	_, err = service.Edits.Tracks.Update(packageName, edit.Id, "production", &androidpublisher.Track{
+		Releases: []*androidpublisher.TrackRelease{{Status: "inProgress", UserFraction: 0.1, VersionCodes: []int64{2}}},
+	}).Do()
+	require.NoError(t, err)
+
+	// Uncommitted changes are not visible.
+	assert.Len(t, server.Track(packageName, "production").Releases, 1)
+
+	_, err = service.Edits.Commit(packageName, edit.Id).Do()
+	require.NoError(t, err)
+
+	releases := server.Track(packageName, "production").Releases
+	require.Len(t, releases, 2)
+	assert.Equal(t, "completed", releases[0].Status)
+	assert.Equal(t, "inProgress", releases[1].Status)
+	assert.Equal(t, 1, server.Commits(packageName))
+	assert.Equal(t, 0, server.OpenEdits(packageName))
+}
+
+func TestServer_resumableUpload(t *testing.T) {
+	server := NewServer()
+	server.AddApp(packageName)
+	service := newService(t, server)
+
+	edit, err := service.Edits.Insert(packageName, &androidpublisher.AppEdit{}).Do()
+	require.NoError(t, err)
+
+	content := []byte("versionCode=7" + strings.Repeat("-", 2*googleapi.MinUploadChunkSize))
+	bundle, err := uploadBundle(service, edit.Id, content, googleapi.ChunkSize(googleapi.MinUploadChunkSize))
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), bundle.VersionCode)
+
+	bundles, err := service.Edits.Bundles.List(packageName, edit.Id).Do()
+	require.NoError(t, err)
+	require.Len(t, bundles.Bundles, 1)
+	assert.Equal(t, bundle.Sha256, bundles.Bundles[0].Sha256)
+}
+
+func TestServer_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []AppOption
+		run     func(service *androidpublisher.Service, editID string) error
+		wantErr string
+	}{
+		{
+			name: "version code already used",
+			opts: []AppOption{WithRelease("production", androidpublisher.TrackRelease{Status: "completed", VersionCodes: []int64{1}})},
+			run: func(service *androidpublisher.Service, editID string) error {
+				_, err := uploadBundle(service, editID, []byte("versionCode=1"))
+				return err
+			},
+			wantErr: "googleapi: Error 403: APK specifies a version code that has already been used., apkUpgradeVersionConflict",
+		},
+		{
+			name: "same artifact uploaded twice",
+			run: func(service *androidpublisher.Service, editID string) error {
+				if _, err := uploadBundle(service, editID, []byte("bundle")); err != nil {
+					return err
+				}
+				_, err := uploadBundle(service, editID, []byte("bundle"))
+				return err
+			},
+			wantErr: "googleapi: Error 403: This artifact was already uploaded with version code 1., apkUpgradeVersionConflict",
+		},
+		{
+			name: "too many completed releases",
+			opts: []AppOption{WithRelease("production", androidpublisher.TrackRelease{Status: "completed", VersionCodes: []int64{1}})},
+			run: func(service *androidpublisher.Service, editID string) error {
+				if _, err := uploadBundle(service, editID, []byte("versionCode=2")); err != nil {
+					return err
+				}
+				_, err := service.Edits.Tracks.Update(packageName, editID, "production", &androidpublisher.Track{
+					Releases: []*androidpublisher.TrackRelease{
+						{Status: "completed", VersionCodes: []int64{1}},
+						{Status: "completed", VersionCodes: []int64{2}},
+					},
+				}).Do()
+				return err
+			},
+			wantErr: "googleapi: Error 400: Too many completed releases specified., releasesTooManyCompletedReleases",
+		},
+		{
+			name: "unknown version code",
+			run: func(service *androidpublisher.Service, editID string) error {
+				_, err := service.Edits.Tracks.Update(packageName, editID, "beta", &androidpublisher.Track{
+					Releases: []*androidpublisher.TrackRelease{{Status: "completed", VersionCodes: []int64{42}}},
+				}).Do()
+				return err
+			},
+			wantErr: "googleapi: Error 403: Version code 42 could not be found., apkNotFound",
+		},
+		{
+			name: "unknown track",
+			run: func(service *androidpublisher.Service, editID string) error {
+				_, err := service.Edits.Tracks.Get(packageName, editID, "qa").Do()
+				return err
+			},
+			wantErr: "googleapi: Error 404: Track not found: qa., notFound",
+		},
+		{
+			name: "changes not sent for review",
+			opts: []AppOption{RequireChangesNotSentForReview()},
+			run: func(service *androidpublisher.Service, editID string) error {
+				_, err := service.Edits.Commit(packageName, editID).Do()
+				return err
+			},
+			wantErr: "googleapi: Error 400: " + ChangesNotSentForReviewMessage + ", badRequest",
+		},
+		{
+			name: "edit deleted by an other commit",
+			run: func(service *androidpublisher.Service, editID string) error {
+				other, err := service.Edits.Insert(packageName, &androidpublisher.AppEdit{}).Do()
+				if err != nil {
+					return err
+				}
+				if _, err := service.Edits.Commit(packageName, other.Id).Do(); err != nil {
+					return err
+				}
+				_, err = service.Edits.Commit(packageName, editID).Do()
+				return err
+			},
+			wantErr: "googleapi: Error 400: " + EditDeletedMessage + ", editDeleted",
+		},
+		{
+			name: "unknown package",
+			run: func(service *androidpublisher.Service, _ string) error {
+				_, err := service.Edits.Insert("io.bitrise.unknown", &androidpublisher.AppEdit{}).Do()
+				return err
+			},
+			wantErr: "googleapi: Error 404: Package not found: io.bitrise.unknown., applicationNotFound",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer()
+			server.AddApp(packageName, tt.opts...)
+			service := newService(t, server)
+
+			edit, err := service.Edits.Insert(packageName, &androidpublisher.AppEdit{}).Do()
+			require.NoError(t, err)
+
+			err = tt.run(service, edit.Id)
+			require.Error(t, err)
+			assert.Equal(t, tt.wantErr, err.Error())
+		})
+	}
+}
+
+func TestServer_changesNotSentForReview(t *testing.T) {
+	server := NewServer()
+	server.AddApp(packageName, RequireChangesNotSentForReview())
+	service := newService(t, server)
+
+	edit, err := service.Edits.Insert(packageName, &androidpublisher.AppEdit{}).Do()
+	require.NoError(t, err)
+
+	_, err = service.Edits.Commit(packageName, edit.Id).ChangesNotSentForReview(true).Do()
+	require.NoError(t, err)
+	assert.Equal(t, 1, server.Commits(packageName))
+}
@@ -0,0 +1,66 @@
+package emulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Messages of the Google Play Developer API errors the emulator responds with.
+const (
+	// ChangesNotSentForReviewMessage is returned on commit if the app requires the changes to be sent for review
+	// manually from the Google Play Console.
+	ChangesNotSentForReviewMessage = "Changes cannot be sent for review automatically. Please set the query parameter changesNotSentForReview to true. Once committed, the changes in this edit can be sent for review from the Google Play Console UI."
+	// TooManyCompletedReleasesMessage is returned if a track update contains more than one completed release.
+	TooManyCompletedReleasesMessage = "Too many completed releases specified."
+	// EditDeletedMessage is returned if the edit was deleted, or an other edit of the app was committed since it was
+	// created.
+	EditDeletedMessage = "This Edit has been deleted."
+)
+
+// apiError is an error response of the API, in the format googleapi.CheckResponse parses.
+type apiError struct {
+	Code    int
+	Message string
+	Reason  string
+}
+
+func (e apiError) Error() string {
+	return fmt.Sprintf("%d: %s, %s", e.Code, e.Message, e.Reason)
+}
+
+type errorItem struct {
+	Message string `json:"message"`
+	Domain  string `json:"domain"`
+	Reason  string `json:"reason"`
+}
+
+type errorBody struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Errors  []errorItem `json:"errors"`
+}
+
+func (e apiError) write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(e.Code)
+	_ = json.NewEncoder(w).Encode(map[string]errorBody{
+		"error": {
+			Code:    e.Code,
+			Message: e.Message,
+			Errors:  []errorItem{{Message: e.Message, Domain: "global", Reason: e.Reason}},
+		},
+	})
+}
+
+func badRequest(reason, format string, v ...interface{}) apiError {
+	return apiError{Code: http.StatusBadRequest, Message: fmt.Sprintf(format, v...), Reason: reason}
+}
+
+func forbidden(reason, format string, v ...interface{}) apiError {
+	return apiError{Code: http.StatusForbidden, Message: fmt.Sprintf(format, v...), Reason: reason}
+}
+
+func notFound(reason, format string, v ...interface{}) apiError {
+	return apiError{Code: http.StatusNotFound, Message: fmt.Sprintf(format, v...), Reason: reason}
+}
@@ -0,0 +1,310 @@
+package emulator
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+// uploadSession is a resumable upload in progress.
+type uploadSession struct {
+	path  string
+	media bytes.Buffer
+}
+
+// uploadSessionStarted is the response of the request initiating a resumable upload.
+type uploadSessionStarted struct {
+	location string
+}
+
+// uploadIncomplete is the response of a chunk of a resumable upload, which is not the last one.
+type uploadIncomplete struct {
+	received int
+}
+
+type uploadHandler func(s *Server, req *http.Request, params []string, media []byte) (interface{}, error)
+
+// upload reads the media of simple, multipart and resumable uploads, and calls the handler once it is complete.
+func upload(handle uploadHandler) func(s *Server, req *http.Request, params []string) (interface{}, error) {
+	return func(s *Server, req *http.Request, params []string) (interface{}, error) {
+		query := req.URL.Query()
+		if uploadID := query.Get("upload_id"); uploadID != "" {
+			return s.uploadChunk(req, params, uploadID, handle)
+		}
+
+		switch query.Get("uploadType") {
+		case "media":
+			media, err := io.ReadAll(req.Body)
+			if err != nil {
+				return nil, badRequest("badRequest", "Failed to read media: %s.", err)
+			}
+			return handle(s, req, params, media)
+		case "multipart":
+			media, err := readMultipartMedia(req)
+			if err != nil {
+				return nil, err
+			}
+			return handle(s, req, params, media)
+		case "resumable":
+			return s.startUploadSession(req), nil
+		default:
+			return nil, badRequest("badRequest", "Invalid upload type: %s.", query.Get("uploadType"))
+		}
+	}
+}
+
+// readMultipartMedia returns the media part of a multipart/related upload, which is the last one, after the JSON
+// metadata.
+func readMultipartMedia(req *http.Request) ([]byte, error) {
+	_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, badRequest("badRequest", "Invalid multipart content type: %s.", err)
+	}
+
+	reader := multipart.NewReader(req.Body, params["boundary"])
+	var parts [][]byte
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, badRequest("badRequest", "Invalid multipart body: %s.", err)
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return nil, badRequest("badRequest", "Invalid multipart body: %s.", err)
+		}
+		parts = append(parts, content)
+	}
+
+	if len(parts) == 0 {
+		return nil, badRequest("badRequest", "Multipart upload without media.")
+	}
+	return parts[len(parts)-1], nil
+}
+
+func (s *Server) startUploadSession(req *http.Request) uploadSessionStarted {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	uploadID := s.newID()
+	s.sessions[uploadID] = &uploadSession{path: req.URL.Path}
+
+	location := *req.URL
+	query := location.Query()
+	query.Del("uploadType")
+	query.Set("upload_id", uploadID)
+	location.RawQuery = query.Encode()
+	return uploadSessionStarted{location: location.String()}
+}
+
+var contentRangePattern = regexp.MustCompile(`^bytes (?:(\d+)-(\d+)|\*)/(\d+|\*)$`)
+
+// uploadChunk appends a chunk to a resumable upload. The Content-Range header tells if it is the last one.
+func (s *Server) uploadChunk(req *http.Request, params []string, uploadID string, handle uploadHandler) (interface{}, error) {
+	chunk, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, badRequest("badRequest", "Failed to read media: %s.", err)
+	}
+
+	matches := contentRangePattern.FindStringSubmatch(req.Header.Get("Content-Range"))
+	if matches == nil {
+		return nil, badRequest("badRequest", "Invalid Content-Range: %s.", req.Header.Get("Content-Range"))
+	}
+
+	s.mu.Lock()
+	session, ok := s.sessions[uploadID]
+	if !ok || session.path != req.URL.Path {
+		s.mu.Unlock()
+		return nil, notFound("notFound", "Upload session not found: %s.", uploadID)
+	}
+	if matches[1] != "" {
+		if offset, _ := strconv.Atoi(matches[1]); offset != session.media.Len() {
+			s.mu.Unlock()
+			return nil, badRequest("badRequest", "Chunk starts at %d, expected %d.", offset, session.media.Len())
+		}
+	}
+	session.media.Write(chunk)
+	received := session.media.Len()
+	if matches[3] == "*" {
+		s.mu.Unlock()
+		return uploadIncomplete{received: received}, nil
+	}
+	delete(s.sessions, uploadID)
+	s.mu.Unlock()
+
+	if total, _ := strconv.Atoi(matches[3]); total != received {
+		return nil, badRequest("badRequest", "Received %d bytes of %d.", received, total)
+	}
+	return handle(s, req, params, session.media.Bytes())
+}
+
+var versionCodePattern = regexp.MustCompile(`versionCode=(\d+)`)
+
+// versionCodeOf returns the version code declared by the artifact. As the emulator does not parse the manifest, test
+// artifacts declare it as `versionCode=<code>` in their content. Other artifacts get the next unused version code.
+func (e *edit) versionCodeOf(media []byte) int64 {
+	head := media
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	if matches := versionCodePattern.FindSubmatch(head); matches != nil {
+		if versionCode, err := strconv.ParseInt(string(matches[1]), 10, 64); err == nil {
+			return versionCode
+		}
+	}
+
+	next := int64(1)
+	for _, bundle := range e.state.Bundles {
+		if bundle.VersionCode >= next {
+			next = bundle.VersionCode + 1
+		}
+	}
+	for _, apk := range e.state.Apks {
+		if apk.VersionCode >= next {
+			next = apk.VersionCode + 1
+		}
+	}
+	return next
+}
+
+// checkVersionCode fails if the version code, or an artifact with the same content, was already uploaded.
+func (e *edit) checkVersionCode(versionCode int64, sha256Hash string) error {
+	for _, bundle := range e.state.Bundles {
+		if bundle.Sha256 == sha256Hash {
+			return forbidden("apkUpgradeVersionConflict", "This artifact was already uploaded with version code %d.", bundle.VersionCode)
+		}
+	}
+	for _, apk := range e.state.Apks {
+		if apk.Binary != nil && apk.Binary.Sha256 == sha256Hash {
+			return forbidden("apkUpgradeVersionConflict", "This artifact was already uploaded with version code %d.", apk.VersionCode)
+		}
+	}
+	if e.hasVersionCode(versionCode) {
+		return forbidden("apkUpgradeVersionConflict", "APK specifies a version code that has already been used.")
+	}
+	return nil
+}
+
+func hashes(media []byte) (string, string) {
+	sha1Sum := sha1.Sum(media)
+	sha256Sum := sha256.Sum256(media)
+	return hex.EncodeToString(sha1Sum[:]), hex.EncodeToString(sha256Sum[:])
+}
+
+func (s *Server) uploadBundle(req *http.Request, params []string, media []byte) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+
+	var deviceTierConfig *androidpublisher.DeviceTierConfig
+	if id := req.URL.Query().Get("deviceTierConfigId"); id != "" {
+		if deviceTierConfig = a.deviceTierConfig(id); deviceTierConfig == nil {
+			return nil, badRequest("badRequest", "Device tier config not found: %s.", id)
+		}
+	}
+
+	sha1Hash, sha256Hash := hashes(media)
+	versionCode := e.versionCodeOf(media)
+	if err := e.checkVersionCode(versionCode, sha256Hash); err != nil {
+		return nil, err
+	}
+
+	bundle := &androidpublisher.Bundle{VersionCode: versionCode, Sha1: sha1Hash, Sha256: sha256Hash}
+	e.state.Bundles = append(e.state.Bundles, bundle)
+	if deviceTierConfig != nil {
+		if e.state.BundleDeviceTierConfigs == nil {
+			e.state.BundleDeviceTierConfigs = map[int64]int64{}
+		}
+		e.state.BundleDeviceTierConfigs[versionCode] = deviceTierConfig.DeviceTierConfigId
+	}
+	return bundle, nil
+}
+
+func (s *Server) uploadApk(_ *http.Request, params []string, media []byte) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+
+	sha1Hash, sha256Hash := hashes(media)
+	versionCode := e.versionCodeOf(media)
+	if err := e.checkVersionCode(versionCode, sha256Hash); err != nil {
+		return nil, err
+	}
+
+	apk := &androidpublisher.Apk{VersionCode: versionCode, Binary: &androidpublisher.ApkBinary{Sha1: sha1Hash, Sha256: sha256Hash}}
+	e.state.Apks = append(e.state.Apks, apk)
+	return apk, nil
+}
+
+func (s *Server) uploadDeobfuscationFile(_ *http.Request, params []string, _ []byte) (interface{}, error) {
+	return s.uploadVersionCodeFile(params, []string{"proguard", "nativeCode"}, func(fileType string) interface{} {
+		return &androidpublisher.DeobfuscationFilesUploadResponse{DeobfuscationFile: &androidpublisher.DeobfuscationFile{SymbolType: fileType}}
+	})
+}
+
+func (s *Server) uploadExpansionFile(_ *http.Request, params []string, media []byte) (interface{}, error) {
+	return s.uploadVersionCodeFile(params, []string{"main", "patch"}, func(string) interface{} {
+		return &androidpublisher.ExpansionFilesUploadResponse{ExpansionFile: &androidpublisher.ExpansionFile{FileSize: int64(len(media))}}
+	})
+}
+
+// uploadVersionCodeFile checks the version code and the file type of a file uploaded for an artifact of the edit.
+func (s *Server) uploadVersionCodeFile(params []string, fileTypes []string, resource func(fileType string) interface{}) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, e, err := s.edit(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+
+	versionCode, err := strconv.ParseInt(params[2], 10, 64)
+	if err != nil || !e.hasVersionCode(versionCode) {
+		return nil, notFound("apkNotFound", "Version code %s could not be found.", params[2])
+	}
+
+	for _, fileType := range fileTypes {
+		if fileType == params[3] {
+			return resource(fileType), nil
+		}
+	}
+	return nil, badRequest("badRequest", "Invalid file type: %s, supported: %s.", params[3], strings.Join(fileTypes, ", "))
+}
+
+func (s *Server) uploadInternalAppSharingArtifact(_ *http.Request, params []string, media []byte) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, err := s.app(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	_, sha256Hash := hashes(media)
+	return &androidpublisher.InternalAppSharingArtifact{
+		DownloadUrl:            fmt.Sprintf("https://play.google.com/apps/test/%s/%s", a.packageName, s.newID()),
+		CertificateFingerprint: strings.ToUpper(sha256Hash[:40]),
+		Sha256:                 sha256Hash,
+	}, nil
+}
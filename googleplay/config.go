@@ -0,0 +1,412 @@
+package googleplay
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-steputils/stepconf"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// The modes of the deployment, see Configs.Mode.
+const (
+	ModeDeploy             = "deploy"
+	ModeInternalAppSharing = "internal_app_sharing"
+	ModeSystemApks         = "system_apks"
+	ModeUploadOnly         = "upload_only"
+	ModeCommitEdit         = "commit_edit"
+	ModeStatus             = "status"
+)
+
+// Configs stores the step's inputs. The env tags are the input keys of the step.
+type Configs struct {
+	Mode                         string          `env:"mode,opt[deploy,internal_app_sharing,system_apks,upload_only,commit_edit,status]"`
+	JSONKeyPath                  stepconf.Secret `env:"service_account_json_key_path"`
+	JSONKeyDownloadHeaders       stepconf.Secret `env:"service_account_json_key_download_headers"`
+	JSONKeySHA256                string          `env:"service_account_json_key_sha256"`
+	ImpersonateServiceAccount    string          `env:"impersonate_service_account"`
+	APIBaseURL                   string          `env:"api_base_url"`
+	APIUploadURL                 string          `env:"api_upload_url"`
+	HTTPSProxy                   stepconf.Secret `env:"https_proxy"`
+	CABundlePath                 string          `env:"ca_bundle_path"`
+	PackageName                  string          `env:"package_name,required"`
+	AppPath                      string          `env:"app_path"`
+	ExpansionfilePath            string          `env:"expansionfile_path"`
+	Track                        string          `env:"track"`
+	UserFraction                 float64         `env:"user_fraction,range]0.0..1.0["`
+	UpdatePriority               int             `env:"update_priority,range[0..5]"`
+	WhatsnewsDir                 string          `env:"whatsnews_dir"`
+	MappingFile                  string          `env:"mapping_file"`
+	ReleaseName                  string          `env:"release_name"`
+	Status                       string          `env:"status"`
+	RetryWithoutSendingToReview  bool            `env:"retry_without_sending_to_review,opt[true,false]"`
+	AckBundleInstallationWarning bool            `env:"ack_bundle_installation_warning,opt[true,false]"`
+	DeviceTierConfigPath         string          `env:"device_tier_config_path"`
+	TestersGoogleGroups          string          `env:"testers_google_groups"`
+	TestersUpdateMode            string          `env:"testers_update_mode,opt[set,append]"`
+	DryRun                       bool            `env:"dry_run,opt[true,false]"`
+	EditRetries                  int             `env:"edit_retries,range[0..10]"`
+	KeepFailedEdit               bool            `env:"keep_failed_edit,opt[true,false]"`
+	Simulate                     bool            `env:"simulate,opt[true,false]"`
+	SimulatedCustomTracks        string          `env:"simulated_custom_tracks"`
+	SystemApkVersionCode         int             `env:"system_apk_version_code"`
+	SystemApkDeviceSpecPath      string          `env:"system_apk_device_spec_path"`
+	GeneratedApksDownload        string          `env:"generated_apks_download,opt[none,universal,splits,all]"`
+	DeployDir                    string          `env:"deploy_dir"`
+	EditStatePath                string          `env:"edit_state_path"`
+	ListingsDir                  string          `env:"listings_dir"`
+	DeploymentReport             string          `env:"deployment_report,opt[none,json,json_and_junit]"`
+	DeploymentTimeout            int             `env:"deployment_timeout,range[0..1440]"`
+	CallTimeout                  int             `env:"call_timeout,range[0..1440]"`
+	IsDebugLog                   bool            `env:"verbose_log,opt[true,false]"`
+	Logger                       log.Logger
+}
+
+// Validate validates the Configs. The app and the track are only required by the modes uploading the app: deploy,
+// upload_only and internal_app_sharing (which requires no track). The service account JSON key is not required in
+// simulate mode. The inputs in use are logged with the Logger of the Configs, or with a default logger if it is not set.
+func (c Configs) Validate() error {
+	if c.Logger == nil {
+		c.Logger = log.NewLogger()
+	}
+
+	if !c.Simulate {
+		if c.JSONKeyPath == "" {
+			return errors.New("service account json key path is required, unless the deployment is simulated")
+		}
+		if err := c.validateJSONKeyPath(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := c.jsonKeyDownloadHeaders(); err != nil {
+		return err
+	}
+
+	if c.ImpersonateServiceAccount != "" && !strings.Contains(c.ImpersonateServiceAccount, "@") {
+		return fmt.Errorf("invalid service account email address to impersonate: %s", c.ImpersonateServiceAccount)
+	}
+
+	if c.Mode == ModeSystemApks {
+		return c.validateSystemApks()
+	}
+
+	if c.Mode == ModeCommitEdit {
+		return c.validateCommitEdit()
+	}
+
+	if c.Mode == ModeStatus {
+		return nil
+	}
+
+	if c.Mode != ModeInternalAppSharing && c.Track == "" {
+		return fmt.Errorf("track is required in %s mode", c.modeName())
+	}
+
+	if c.Mode == ModeUploadOnly && c.DeployDir == "" {
+		return errors.New("deploy directory is required to persist the edit")
+	}
+
+	if err := c.validateWhatsnewsDir(); err != nil {
+		return err
+	}
+
+	if err := c.validateMappingFile(); err != nil {
+		return err
+	}
+
+	if err := c.validateDeviceTierConfigPath(); err != nil {
+		return err
+	}
+
+	if err := c.validateTestersGoogleGroups(); err != nil {
+		return err
+	}
+
+	if c.shouldDownloadGeneratedApks() && c.DeployDir == "" {
+		return errors.New("deploy directory is required to download generated APKs")
+	}
+
+	return c.validateApps()
+}
+
+// modeName returns the configured mode, defaulting to deploy.
+func (c Configs) modeName() string {
+	if c.Mode == "" {
+		return ModeDeploy
+	}
+	return c.Mode
+}
+
+// validateJSONKeyPath validates if service_account_json_key_path input value exists if defined and has file:// URL scheme.
+func (c Configs) validateJSONKeyPath() error {
+	if !strings.HasPrefix(string(c.JSONKeyPath), "file://") {
+		return nil
+	}
+
+	pth := strings.TrimPrefix(string(c.JSONKeyPath), "file://")
+	if exist, err := pathutil.IsPathExists(pth); err != nil {
+		return fmt.Errorf("failed to check if json key path exist at: %s, error: %s", pth, err)
+	} else if !exist {
+		return errors.New("json key path not exist at: " + pth)
+	}
+	return nil
+}
+
+// jsonKeyDownloadHeaders parses the headers to send when downloading the json key, given as a newline separated list
+// in `Name: value` format.
+func (c Configs) jsonKeyDownloadHeaders() (http.Header, error) {
+	// Not parsed with parseInputList, as that logs the elements and header values may contain credentials.
+	headers := http.Header{}
+	for _, line := range splitElements([]string{string(c.JSONKeyDownloadHeaders)}, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, value, found := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid json key download header, expected format: `Name: value`")
+		}
+		headers.Add(name, strings.TrimSpace(value))
+	}
+	return headers, nil
+}
+
+// validateSystemApks validates the inputs required to generate system APKs.
+func (c Configs) validateSystemApks() error {
+	if c.SystemApkVersionCode <= 0 {
+		return errors.New("system APK version code is required to generate system APKs")
+	}
+
+	if c.SystemApkDeviceSpecPath == "" {
+		return errors.New("device spec path is required to generate system APKs")
+	}
+	if exist, err := pathutil.IsPathExists(c.SystemApkDeviceSpecPath); err != nil {
+		return fmt.Errorf("failed to check if device spec exist at: %s, error: %s", c.SystemApkDeviceSpecPath, err)
+	} else if !exist {
+		return errors.New("device spec not exist at: " + c.SystemApkDeviceSpecPath)
+	}
+
+	if c.DeployDir == "" {
+		return errors.New("deploy directory is required to generate system APKs")
+	}
+	return nil
+}
+
+// validateCommitEdit validates the inputs required to commit a persisted edit.
+func (c Configs) validateCommitEdit() error {
+	if c.EditStatePath == "" {
+		return errors.New("edit state path is required to commit a persisted edit")
+	}
+	if exist, err := pathutil.IsPathExists(c.EditStatePath); err != nil {
+		return fmt.Errorf("failed to check if edit state exist at: %s, error: %s", c.EditStatePath, err)
+	} else if !exist {
+		return errors.New("edit state not exist at: " + c.EditStatePath)
+	}
+
+	if err := c.validateWhatsnewsDir(); err != nil {
+		return err
+	}
+	return c.validateListingsDir()
+}
+
+// validateListingsDir validates if listings_dir input value exists if provided.
+func (c Configs) validateListingsDir() error {
+	if c.ListingsDir == "" {
+		return nil
+	}
+
+	if exist, err := pathutil.IsDirExists(c.ListingsDir); err != nil {
+		return fmt.Errorf("failed to check if listings directory exist at: %s, error: %s", c.ListingsDir, err)
+	} else if !exist {
+		return errors.New("listings directory not exist at: " + c.ListingsDir)
+	}
+
+	c.Logger.Infof("Using listings from: %v", c.ListingsDir)
+	return nil
+}
+
+// validateWhatsnewsDir validates if whatsnews_dir input value exists if provided.
+func (c Configs) validateWhatsnewsDir() error {
+	if c.WhatsnewsDir == "" {
+		return nil
+	}
+
+	if exist, err := pathutil.IsDirExists(c.WhatsnewsDir); err != nil {
+		return fmt.Errorf("failed to check if what's new directory exist at: %s, error: %s", c.WhatsnewsDir, err)
+	} else if !exist {
+		return errors.New("what's new directory not exist at: " + c.WhatsnewsDir)
+	}
+
+	c.Logger.Infof("Using what's new data from: %v", c.WhatsnewsDir)
+	return nil
+}
+
+// validateMappingFile validates if mapping_file input value exists if provided.
+func (c Configs) validateMappingFile() error {
+	if c.MappingFile == "" {
+		return nil
+	}
+
+	for _, path := range c.parseInputList(c.MappingFile) {
+		if exist, err := pathutil.IsPathExists(path); err != nil {
+			return fmt.Errorf("failed to check if mapping file exist at: %s, error: %s", path, err)
+		} else if !exist {
+			return errors.New("mapping file doesn't exist at: " + path)
+		}
+
+		c.Logger.Infof("Using mapping file from: %v", path)
+	}
+	return nil
+}
+
+// validateDeviceTierConfigPath validates if device_tier_config_path input value exists if provided.
+func (c Configs) validateDeviceTierConfigPath() error {
+	if c.DeviceTierConfigPath == "" {
+		return nil
+	}
+
+	if exist, err := pathutil.IsPathExists(c.DeviceTierConfigPath); err != nil {
+		return fmt.Errorf("failed to check if device tier config exist at: %s, error: %s", c.DeviceTierConfigPath, err)
+	} else if !exist {
+		return errors.New("device tier config not exist at: " + c.DeviceTierConfigPath)
+	}
+
+	c.Logger.Infof("Using device tier config from: %v", c.DeviceTierConfigPath)
+	return nil
+}
+
+// validateTestersGoogleGroups validates if testers_google_groups input values are email addresses.
+func (c Configs) validateTestersGoogleGroups() error {
+	for _, group := range c.parseInputList(c.TestersGoogleGroups) {
+		if !strings.Contains(group, "@") {
+			return fmt.Errorf("invalid Google Group email address: %s", group)
+		}
+	}
+	return nil
+}
+
+func splitElements(list []string, sep string) (s []string) {
+	for _, e := range list {
+		s = append(s, strings.Split(e, sep)...)
+	}
+	return
+}
+
+func (c Configs) parseInputList(list string) (elements []string) {
+	c.Logger.Debugf("Parsing list input: '%v'", list)
+	list = strings.TrimSpace(list)
+	if len(list) == 0 {
+		return nil
+	}
+
+	s := []string{list}
+	for _, sep := range []string{"\n", `\n`, "|"} {
+		s = splitElements(s, sep)
+	}
+
+	for _, element := range s {
+		element = strings.TrimSpace(element)
+		if len(element) > 0 {
+			elements = append(elements, element)
+			c.Logger.Debugf("Found element: %v", element)
+		}
+	}
+	return
+}
+
+// appPaths returns the app to deploy, by preferring .aab files.
+func (c Configs) appPaths() ([]string, []string) {
+	var apks, aabs, warnings []string
+	for _, pth := range c.parseInputList(c.AppPath) {
+		pth = strings.TrimSpace(pth)
+		ext := strings.ToLower(filepath.Ext(pth))
+		switch ext {
+		case ".aab":
+			aabs = append(aabs, pth)
+		case ".apk":
+			apks = append(apks, pth)
+		default:
+			warnings = append(warnings, fmt.Sprintf("unknown app path extension in path: %s, supported extensions: .apk, .aab", pth))
+		}
+	}
+
+	if len(aabs) > 0 && len(apks) > 0 {
+		warnings = append(warnings, fmt.Sprintf("Both .aab and .apk files provided, using the .aab file(s): %s", strings.Join(aabs, ",")))
+	}
+
+	if len(aabs) > 0 {
+		return aabs, warnings
+	}
+
+	return apks, warnings
+}
+
+// shouldDownloadGeneratedApks returns true if downloading the generated APKs is requested and the deployed apps are
+// app bundles.
+func (c Configs) shouldDownloadGeneratedApks() bool {
+	if c.GeneratedApksDownload == "" || c.GeneratedApksDownload == generatedApksDownloadNone {
+		return false
+	}
+
+	apps, _ := c.appPaths()
+	return len(apps) > 0 && strings.ToLower(filepath.Ext(apps[0])) == ".aab"
+}
+
+func (c Configs) mappingPaths() []string {
+	var mappingPaths []string
+	for _, path := range strings.Split(c.MappingFile, "|") {
+		if trimmed := strings.TrimSpace(path); trimmed != "" {
+			mappingPaths = append(mappingPaths, trimmed)
+		}
+	}
+	return mappingPaths
+}
+
+// validateApps validates if files provided via app_path are existing files,
+// if app_path is empty it validates if files provided via app_path input are existing .apk or .aab files.
+func (c Configs) validateApps() error {
+	apps, warnings := c.appPaths()
+	for _, warn := range warnings {
+		c.Logger.Warnf(warn)
+	}
+
+	if len(apps) == 0 {
+		return fmt.Errorf("no app provided, app path is required in %s mode", c.modeName())
+	}
+
+	for _, pth := range apps {
+		if exist, err := pathutil.IsPathExists(pth); err != nil {
+			return fmt.Errorf("failed to check if app exist at: %s, error: %s", pth, err)
+		} else if !exist {
+			return errors.New("app not exist at: " + pth)
+		}
+		c.Logger.Infof("Using app from: %v", pth)
+	}
+
+	return nil
+}
+
+// expansionFiles gets the expansion files from the received configuration. Returns true and the entries (type and
+// path) of them when any found, false or error otherwise.
+func (c Configs) expansionFiles(appPaths []string) ([]string, error) {
+	// "main:/file/path/1.obb|patch:/file/path/2.obb"
+	var expansionFileEntries = []string{}
+	if strings.TrimSpace(c.ExpansionfilePath) != "" {
+		expansionFileEntries = strings.Split(c.ExpansionfilePath, "|")
+
+		if len(appPaths) != len(expansionFileEntries) {
+			return []string{}, fmt.Errorf("mismatching number of APKs(%d) and Expansionfiles(%d)", len(appPaths), len(expansionFileEntries))
+		}
+
+		c.Logger.Infof("Found %v expansion file(s) to upload.", len(expansionFileEntries))
+		for i, expansionFile := range expansionFileEntries {
+			c.Logger.Debugf("%v - %v", i+1, expansionFile)
+		}
+	}
+	return expansionFileEntries, nil
+}
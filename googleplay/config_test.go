@@ -1,4 +1,4 @@
-package main
+package googleplay
 
 import (
 	"os"
@@ -248,3 +248,100 @@ func Test_expansionFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigs_Validate_perMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	appPath := filepath.Join(tmpDir, "app.aab")
+	deviceSpecPath := filepath.Join(tmpDir, "device-spec.json")
+	editStatePath := filepath.Join(tmpDir, editStateFileName)
+	for _, pth := range []string{appPath, deviceSpecPath, editStatePath} {
+		if err := os.WriteFile(pth, []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		config  Configs
+		wantErr string
+	}{
+		{"deploy", Configs{AppPath: appPath, Track: "beta"}, ""},
+		{"deploy without track", Configs{AppPath: appPath}, "track is required in deploy mode"},
+		{"deploy without app", Configs{Track: "beta"}, "no app provided, app path is required in deploy mode"},
+		{"upload_only without track", Configs{Mode: ModeUploadOnly, AppPath: appPath, DeployDir: tmpDir}, "track is required in upload_only mode"},
+		{"internal_app_sharing without track", Configs{Mode: ModeInternalAppSharing, AppPath: appPath}, ""},
+		{"internal_app_sharing without app", Configs{Mode: ModeInternalAppSharing}, "no app provided, app path is required in internal_app_sharing mode"},
+		{"system_apks without app and track", Configs{Mode: ModeSystemApks, SystemApkVersionCode: 1, SystemApkDeviceSpecPath: deviceSpecPath, DeployDir: tmpDir}, ""},
+		{"commit_edit without app and track", Configs{Mode: ModeCommitEdit, EditStatePath: editStatePath}, ""},
+		{"status without app and track", Configs{Mode: ModeStatus}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.JSONKeyPath = "https://example.com/key.json"
+			tt.config.Logger = log.NewLogger()
+			err := tt.config.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Configs.Validate() error = %v, want no error", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("Configs.Validate() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigs_Validate_withoutLogger(t *testing.T) {
+	tmpDir := t.TempDir()
+	appPath := filepath.Join(tmpDir, "app.aab")
+	mappingPath := filepath.Join(tmpDir, "mapping.txt")
+	for _, pth := range []string{appPath, mappingPath} {
+		if err := os.WriteFile(pth, []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := Configs{
+		JSONKeyPath:         "https://example.com/key.json",
+		AppPath:             appPath,
+		Track:               "beta",
+		WhatsnewsDir:        tmpDir,
+		MappingFile:         mappingPath,
+		TestersGoogleGroups: "qa@googlegroups.com",
+	}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Configs.Validate() error = %v, want no error", err)
+	}
+}
+
+func TestConfigs_Validate_jsonKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	appPath := filepath.Join(tmpDir, "app.aab")
+	if err := os.WriteFile(appPath, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	missingKeyPath := stepconf.Secret("file://" + filepath.Join(tmpDir, "key.json"))
+
+	tests := []struct {
+		name        string
+		jsonKeyPath stepconf.Secret
+		simulate    bool
+		wantErr     string
+	}{
+		{"no key", "", false, "service account json key path is required, unless the deployment is simulated"},
+		{"missing key file", missingKeyPath, false, "json key path not exist at: " + filepath.Join(tmpDir, "key.json")},
+		{"no key in simulate mode", "", true, ""},
+		{"missing key file in simulate mode", missingKeyPath, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Configs{JSONKeyPath: tt.jsonKeyPath, Simulate: tt.simulate, AppPath: appPath, Track: "beta", Logger: log.NewLogger()}
+			err := config.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Configs.Validate() error = %v, want no error", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("Configs.Validate() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
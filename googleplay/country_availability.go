@@ -0,0 +1,66 @@
+package googleplay
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+const (
+	trackCountriesKey   = "GOOGLE_PLAY_TRACK_COUNTRIES"
+	trackRestOfWorldKey = "GOOGLE_PLAY_TRACK_REST_OF_WORLD"
+)
+
+// reportCountryAvailability prints the countries the given track is available in. Returns them as outputs.
+func (p *Publisher) reportCountryAvailability(ctx context.Context, configs Configs, service *androidpublisher.Service, appEdit *androidpublisher.AppEdit) (Outputs, error) {
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	editsCountryAvailabilityService := androidpublisher.NewEditsCountryavailabilityService(service)
+	availability, err := editsCountryAvailabilityService.Get(configs.PackageName, appEdit.Id, configs.Track).Context(callCtx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get country availability of track %s, error: %w", configs.Track, err)
+	}
+
+	countries := countryCodes(availability)
+	if availability.SyncWithProduction {
+		p.logger.Printf(" country availability is synced with the production track")
+	}
+	if message, available := countryAvailabilityMessage(configs.Track, countries, availability.RestOfWorld); available {
+		p.logger.Printf(" %s", message)
+	} else {
+		p.logger.Warnf("%s", message)
+	}
+
+	return Outputs{
+		trackCountriesKey:   strings.Join(countries, "|"),
+		trackRestOfWorldKey: strconv.FormatBool(availability.RestOfWorld),
+	}, nil
+}
+
+// countryAvailabilityMessage describes where the track is available. Returns false if it is not available anywhere.
+func countryAvailabilityMessage(track string, countries []string, restOfWorld bool) (string, bool) {
+	switch {
+	case len(countries) == 0 && restOfWorld:
+		return "available in every country", true
+	case len(countries) == 0:
+		return fmt.Sprintf("%s track is not available in any country", track), false
+	case restOfWorld:
+		return fmt.Sprintf("available in %d countries and the rest of the world: %s", len(countries), strings.Join(countries, ", ")), true
+	default:
+		return fmt.Sprintf("available in %d countries: %s", len(countries), strings.Join(countries, ", ")), true
+	}
+}
+
+// countryCodes returns the sorted country codes of the given availability.
+func countryCodes(availability *androidpublisher.TrackCountryAvailability) []string {
+	var codes []string
+	for _, country := range availability.Countries {
+		codes = append(codes, country.CountryCode)
+	}
+	sort.Strings(codes)
+	return codes
+}
@@ -0,0 +1,50 @@
+package googleplay
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+func Test_countryCodes(t *testing.T) {
+	tests := []struct {
+		name         string
+		availability *androidpublisher.TrackCountryAvailability
+		want         []string
+	}{
+		{"no countries", &androidpublisher.TrackCountryAvailability{RestOfWorld: true}, nil},
+		{
+			"sorted country codes",
+			&androidpublisher.TrackCountryAvailability{Countries: []*androidpublisher.TrackTargetedCountry{{CountryCode: "US"}, {CountryCode: "DE"}, {CountryCode: "HU"}}},
+			[]string{"DE", "HU", "US"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, countryCodes(tt.availability))
+		})
+	}
+}
+
+func Test_countryAvailabilityMessage(t *testing.T) {
+	tests := []struct {
+		name          string
+		countries     []string
+		restOfWorld   bool
+		wantMessage   string
+		wantAvailable bool
+	}{
+		{"rest of world only", nil, true, "available in every country", true},
+		{"nowhere", nil, false, "beta track is not available in any country", false},
+		{"countries", []string{"DE", "HU"}, false, "available in 2 countries: DE, HU", true},
+		{"countries and rest of world", []string{"DE"}, true, "available in 1 countries and the rest of the world: DE", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, available := countryAvailabilityMessage("beta", tt.countries, tt.restOfWorld)
+			assert.Equal(t, tt.wantMessage, message)
+			assert.Equal(t, tt.wantAvailable, available)
+		})
+	}
+}
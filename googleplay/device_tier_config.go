@@ -0,0 +1,99 @@
+package googleplay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+// ensureDeviceTierConfig returns the ID of the device tier config declared in the given JSON file. The config is
+// created if an identical one does not exist yet.
+func (p *Publisher) ensureDeviceTierConfig(ctx context.Context, service *androidpublisher.Service, packageName string, pth string) (string, error) {
+	config, err := readDeviceTierConfig(pth)
+	if err != nil {
+		return "", err
+	}
+
+	deviceTierConfigsService := androidpublisher.NewApplicationsDeviceTierConfigsService(service)
+
+	listCtx, cancelList := callContext(ctx)
+	defer cancelList()
+	var existing *androidpublisher.DeviceTierConfig
+	if err := deviceTierConfigsService.List(packageName).Pages(listCtx, func(resp *androidpublisher.ListDeviceTierConfigsResponse) error {
+		if existing == nil {
+			existing = findDeviceTierConfig(resp.DeviceTierConfigs, config)
+		}
+		return nil
+	}); err != nil {
+		return "", fmt.Errorf("failed to list device tier configs, error: %w", err)
+	}
+
+	if existing != nil {
+		p.logger.Printf(" using existing device tier config: %d", existing.DeviceTierConfigId)
+		return strconv.FormatInt(existing.DeviceTierConfigId, 10), nil
+	}
+
+	createCtx, cancelCreate := callContext(ctx)
+	defer cancelCreate()
+	created, err := deviceTierConfigsService.Create(packageName, config).Context(createCtx).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to create device tier config, error: %w", err)
+	}
+	p.logger.Printf(" created device tier config: %d", created.DeviceTierConfigId)
+	return strconv.FormatInt(created.DeviceTierConfigId, 10), nil
+}
+
+// readDeviceTierConfig reads a device tier config from the given JSON file, in the format of the
+// Google Play Developer API's DeviceTierConfig resource.
+func readDeviceTierConfig(pth string) (*androidpublisher.DeviceTierConfig, error) {
+	content, err := os.ReadFile(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device tier config (%s), error: %w", pth, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+
+	var config androidpublisher.DeviceTierConfig
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse device tier config (%s), error: %w", pth, err)
+	}
+
+	if len(config.DeviceGroups) == 0 {
+		return nil, fmt.Errorf("device tier config (%s) does not specify any deviceGroups", pth)
+	}
+	// The ID is assigned by Google Play.
+	config.DeviceTierConfigId = 0
+	return &config, nil
+}
+
+// findDeviceTierConfig returns the config with the same content as the given one, or nil if there is none.
+func findDeviceTierConfig(configs []*androidpublisher.DeviceTierConfig, config *androidpublisher.DeviceTierConfig) *androidpublisher.DeviceTierConfig {
+	key, err := deviceTierConfigKey(config)
+	if err != nil {
+		return nil
+	}
+
+	for _, candidate := range configs {
+		if candidateKey, err := deviceTierConfigKey(candidate); err == nil && candidateKey == key {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// deviceTierConfigKey returns the JSON representation of the config without its ID.
+func deviceTierConfigKey(config *androidpublisher.DeviceTierConfig) (string, error) {
+	withoutID := *config
+	withoutID.DeviceTierConfigId = 0
+	content, err := json.Marshal(withoutID)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
@@ -0,0 +1,113 @@
+package googleplay
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-google-play-deploy/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+const deviceTierConfigJSON = `{
+  "deviceGroups": [{"name": "high", "deviceSelectors": [{"deviceRam": {"minBytes": "4294967296"}}]}],
+  "deviceTierSet": {"deviceTiers": [{"deviceGroupNames": ["high"], "level": 1}]}
+}`
+
+func Test_readDeviceTierConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	validPth := filepath.Join(dir, "valid.json")
+	require.NoError(t, os.WriteFile(validPth, []byte(deviceTierConfigJSON), 0600))
+	config, err := readDeviceTierConfig(validPth)
+	require.NoError(t, err)
+	assert.Equal(t, "high", config.DeviceGroups[0].Name)
+	assert.Equal(t, int64(1), config.DeviceTierSet.DeviceTiers[0].Level)
+
+	emptyPth := filepath.Join(dir, "empty.json")
+	require.NoError(t, os.WriteFile(emptyPth, []byte(`{}`), 0600))
+	_, err = readDeviceTierConfig(emptyPth)
+	assert.Error(t, err)
+
+	unknownPth := filepath.Join(dir, "unknown.json")
+	require.NoError(t, os.WriteFile(unknownPth, []byte(`{"groups": []}`), 0600))
+	_, err = readDeviceTierConfig(unknownPth)
+	assert.Error(t, err)
+}
+
+func Test_findDeviceTierConfig(t *testing.T) {
+	config := &androidpublisher.DeviceTierConfig{
+		DeviceGroups: []*androidpublisher.DeviceGroup{{Name: "high"}},
+	}
+	configs := []*androidpublisher.DeviceTierConfig{
+		{DeviceTierConfigId: 1, DeviceGroups: []*androidpublisher.DeviceGroup{{Name: "low"}}},
+		{DeviceTierConfigId: 2, DeviceGroups: []*androidpublisher.DeviceGroup{{Name: "high"}}},
+	}
+
+	found := findDeviceTierConfig(configs, config)
+	require.NotNil(t, found)
+	assert.Equal(t, int64(2), found.DeviceTierConfigId)
+
+	assert.Nil(t, findDeviceTierConfig(configs[:1], config))
+}
+
+func TestPublisher_executeEdit_deviceTierConfig(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	dir := t.TempDir()
+	pth := filepath.Join(dir, "device_tier_config.json")
+	require.NoError(t, os.WriteFile(pth, []byte(deviceTierConfigJSON), 0600))
+	config, err := readDeviceTierConfig(pth)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		existing []androidpublisher.DeviceTierConfig
+		wantID   func(t *testing.T, configs []*androidpublisher.DeviceTierConfig) int64
+	}{
+		{
+			name:     "creates the config",
+			existing: []androidpublisher.DeviceTierConfig{{DeviceTierConfigId: 42, DeviceGroups: []*androidpublisher.DeviceGroup{{Name: "low"}}}},
+			wantID: func(t *testing.T, configs []*androidpublisher.DeviceTierConfig) int64 {
+				require.Len(t, configs, 2)
+				assert.Equal(t, "high", configs[1].DeviceGroups[0].Name)
+				return configs[1].DeviceTierConfigId
+			},
+		},
+		{
+			name: "identical config exists",
+			existing: []androidpublisher.DeviceTierConfig{
+				{DeviceTierConfigId: 42, DeviceGroups: config.DeviceGroups, DeviceTierSet: config.DeviceTierSet},
+			},
+			wantID: func(t *testing.T, configs []*androidpublisher.DeviceTierConfig) int64 {
+				require.Len(t, configs, 1)
+				return 42
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []emulator.AppOption
+			for _, existing := range tt.existing {
+				options = append(options, emulator.WithDeviceTierConfig(existing))
+			}
+
+			configs := Configs{
+				PackageName:          packageName,
+				AppPath:              writeBundles(t, "2"),
+				Track:                "beta",
+				DeviceTierConfigPath: pth,
+				Logger:               log.NewLogger(),
+			}
+			server, publisher, service := newEmulatedPublisher(t, configs, options...)
+
+			require.NoError(t, publisher.executeEdit(context.Background(), service, configs, Outputs{}, false, false))
+
+			wantID := tt.wantID(t, server.DeviceTierConfigs(packageName))
+			assert.Equal(t, wantID, server.BundleDeviceTierConfig(packageName, 2))
+		})
+	}
+}
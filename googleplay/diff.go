@@ -0,0 +1,449 @@
+package googleplay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+const (
+	changeAdded    = "added"
+	changeRemoved  = "removed"
+	changeReplaced = "replaced"
+	changeUpdated  = "updated"
+)
+
+const (
+	dryRunDiffFileName = "google-play-dry-run-diff.json"
+	dryRunDiffPathKey  = "GOOGLE_PLAY_DRY_RUN_DIFF_PATH"
+)
+
+// editState is a snapshot of the parts of an app the step is able to change within an edit.
+type editState struct {
+	Tracks   []*androidpublisher.Track
+	Listings []*androidpublisher.Listing
+	Testers  map[string]*androidpublisher.Testers
+	Details  *androidpublisher.AppDetails
+}
+
+// fieldChange describes the old and new value of a single field.
+type fieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// releaseChange describes a release added to, removed from or updated on a track.
+type releaseChange struct {
+	Track        string        `json:"track"`
+	Change       string        `json:"change"`
+	Name         string        `json:"name,omitempty"`
+	VersionCodes []int64       `json:"versionCodes,omitempty"`
+	Fields       []fieldChange `json:"fields,omitempty"`
+	NotesChanged []string      `json:"notesChanged,omitempty"`
+}
+
+// listingChange describes a store listing added, removed or updated for a language.
+type listingChange struct {
+	Language string        `json:"language"`
+	Change   string        `json:"change"`
+	Fields   []fieldChange `json:"fields,omitempty"`
+}
+
+// testersChange describes the change of the Google Groups set as testers of a track.
+type testersChange struct {
+	Track string   `json:"track"`
+	Old   []string `json:"old"`
+	New   []string `json:"new"`
+}
+
+// editDiff is the difference between two edit states.
+type editDiff struct {
+	Releases []releaseChange `json:"releases"`
+	Listings []listingChange `json:"listings"`
+	Testers  []testersChange `json:"testers"`
+	Details  []fieldChange   `json:"details"`
+}
+
+// isEmpty returns true if the diff does not contain any change.
+func (d editDiff) isEmpty() bool {
+	return len(d.Releases) == 0 && len(d.Listings) == 0 && len(d.Testers) == 0 && len(d.Details) == 0
+}
+
+// fetchEditState fetches the tracks, listings, testers and details of the app as seen in the given edit.
+func (p *Publisher) fetchEditState(ctx context.Context, configs Configs, service *androidpublisher.Service, appEdit *androidpublisher.AppEdit) (editState, error) {
+	state := editState{Testers: map[string]*androidpublisher.Testers{}}
+
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	tracks, err := androidpublisher.NewEditsTracksService(service).List(configs.PackageName, appEdit.Id).Context(callCtx).Do()
+	if err != nil {
+		return editState{}, fmt.Errorf("failed to list tracks, error: %w", err)
+	}
+	state.Tracks = tracks.Tracks
+
+	listingsCtx, cancelListings := callContext(ctx)
+	defer cancelListings()
+	listings, err := androidpublisher.NewEditsListingsService(service).List(configs.PackageName, appEdit.Id).Context(listingsCtx).Do()
+	if err != nil {
+		return editState{}, fmt.Errorf("failed to list listings, error: %w", err)
+	}
+	state.Listings = listings.Listings
+
+	detailsCtx, cancelDetails := callContext(ctx)
+	defer cancelDetails()
+	details, err := androidpublisher.NewEditsDetailsService(service).Get(configs.PackageName, appEdit.Id).Context(detailsCtx).Do()
+	if err != nil {
+		return editState{}, fmt.Errorf("failed to get app details, error: %w", err)
+	}
+	state.Details = details
+
+	editsTestersService := androidpublisher.NewEditsTestersService(service)
+	for _, track := range state.Tracks {
+		// Testers can not be managed on every track (for example on production), those are skipped.
+		testersCtx, cancelTesters := callContext(ctx)
+		testers, err := editsTestersService.Get(configs.PackageName, appEdit.Id, track.Track).Context(testersCtx).Do()
+		cancelTesters()
+		if err != nil {
+			if ctx.Err() != nil {
+				return editState{}, fmt.Errorf("failed to get testers of track %s, error: %w", track.Track, err)
+			}
+			p.logger.Debugf("Unable to fetch testers of track %s, error: %s", track.Track, err)
+			continue
+		}
+		state.Testers[track.Track] = testers
+	}
+
+	return state, nil
+}
+
+// diffEditStates returns the changes needed to get from the before state to the after state.
+func diffEditStates(before, after editState) editDiff {
+	return editDiff{
+		Releases: diffTracks(before.Tracks, after.Tracks),
+		Listings: diffListings(before.Listings, after.Listings),
+		Testers:  diffTesters(before.Testers, after.Testers),
+		Details:  diffDetails(before.Details, after.Details),
+	}
+}
+
+func diffTracks(before, after []*androidpublisher.Track) []releaseChange {
+	beforeByName := map[string]*androidpublisher.Track{}
+	for _, track := range before {
+		beforeByName[track.Track] = track
+	}
+	afterByName := map[string]*androidpublisher.Track{}
+	for _, track := range after {
+		afterByName[track.Track] = track
+	}
+
+	var changes []releaseChange
+	for _, name := range sortedKeys(beforeByName, afterByName) {
+		var beforeReleases, afterReleases []*androidpublisher.TrackRelease
+		if track, ok := beforeByName[name]; ok {
+			beforeReleases = track.Releases
+		}
+		if track, ok := afterByName[name]; ok {
+			afterReleases = track.Releases
+		}
+		changes = append(changes, diffReleases(name, beforeReleases, afterReleases)...)
+	}
+	return changes
+}
+
+// diffReleases compares the releases of a single track. Releases are identified by their version codes,
+// a removed release is reported as replaced if a new release was added to the same track.
+func diffReleases(track string, before, after []*androidpublisher.TrackRelease) []releaseChange {
+	beforeByKey := map[string]*androidpublisher.TrackRelease{}
+	for _, release := range before {
+		beforeByKey[releaseKey(release)] = release
+	}
+	afterByKey := map[string]*androidpublisher.TrackRelease{}
+	for _, release := range after {
+		afterByKey[releaseKey(release)] = release
+	}
+
+	var added, removed, updated []releaseChange
+	for _, key := range sortedKeys(beforeByKey, afterByKey) {
+		oldRelease, hasOld := beforeByKey[key]
+		newRelease, hasNew := afterByKey[key]
+
+		switch {
+		case !hasOld:
+			added = append(added, releaseChange{
+				Track:        track,
+				Change:       changeAdded,
+				Name:         newRelease.Name,
+				VersionCodes: newRelease.VersionCodes,
+				Fields:       diffReleaseFields(&androidpublisher.TrackRelease{}, newRelease),
+				NotesChanged: diffReleaseNotes(nil, newRelease.ReleaseNotes),
+			})
+		case !hasNew:
+			removed = append(removed, releaseChange{
+				Track:        track,
+				Change:       changeRemoved,
+				Name:         oldRelease.Name,
+				VersionCodes: oldRelease.VersionCodes,
+			})
+		default:
+			fields := diffReleaseFields(oldRelease, newRelease)
+			notes := diffReleaseNotes(oldRelease.ReleaseNotes, newRelease.ReleaseNotes)
+			if len(fields) > 0 || len(notes) > 0 {
+				updated = append(updated, releaseChange{
+					Track:        track,
+					Change:       changeUpdated,
+					Name:         newRelease.Name,
+					VersionCodes: newRelease.VersionCodes,
+					Fields:       fields,
+					NotesChanged: notes,
+				})
+			}
+		}
+	}
+
+	if len(added) > 0 {
+		for i := range removed {
+			removed[i].Change = changeReplaced
+		}
+	}
+
+	var changes []releaseChange
+	changes = append(changes, added...)
+	changes = append(changes, updated...)
+	changes = append(changes, removed...)
+	return changes
+}
+
+func releaseKey(release *androidpublisher.TrackRelease) string {
+	if len(release.VersionCodes) == 0 {
+		return "name:" + release.Name
+	}
+
+	codes := make([]int64, len(release.VersionCodes))
+	copy(codes, release.VersionCodes)
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	var parts []string
+	for _, code := range codes {
+		parts = append(parts, strconv.FormatInt(code, 10))
+	}
+	return "codes:" + strings.Join(parts, ",")
+}
+
+func diffReleaseFields(before, after *androidpublisher.TrackRelease) []fieldChange {
+	var changes []fieldChange
+	changes = appendFieldChange(changes, "name", before.Name, after.Name)
+	changes = appendFieldChange(changes, "status", before.Status, after.Status)
+	changes = appendFieldChange(changes, "userFraction", formatFraction(before.UserFraction), formatFraction(after.UserFraction))
+	changes = appendFieldChange(changes, "inAppUpdatePriority", formatPriority(before.InAppUpdatePriority), formatPriority(after.InAppUpdatePriority))
+	return changes
+}
+
+// diffReleaseNotes returns the languages whose release notes were added, removed or changed.
+func diffReleaseNotes(before, after []*androidpublisher.LocalizedText) []string {
+	beforeByLanguage := map[string]string{}
+	for _, note := range before {
+		beforeByLanguage[note.Language] = note.Text
+	}
+	afterByLanguage := map[string]string{}
+	for _, note := range after {
+		afterByLanguage[note.Language] = note.Text
+	}
+
+	var languages []string
+	for _, language := range sortedKeys(beforeByLanguage, afterByLanguage) {
+		oldText, hasOld := beforeByLanguage[language]
+		newText, hasNew := afterByLanguage[language]
+		if hasOld != hasNew || oldText != newText {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+func diffListings(before, after []*androidpublisher.Listing) []listingChange {
+	beforeByLanguage := map[string]*androidpublisher.Listing{}
+	for _, listing := range before {
+		beforeByLanguage[listing.Language] = listing
+	}
+	afterByLanguage := map[string]*androidpublisher.Listing{}
+	for _, listing := range after {
+		afterByLanguage[listing.Language] = listing
+	}
+
+	var changes []listingChange
+	for _, language := range sortedKeys(beforeByLanguage, afterByLanguage) {
+		oldListing, hasOld := beforeByLanguage[language]
+		newListing, hasNew := afterByLanguage[language]
+
+		change := changeUpdated
+		switch {
+		case !hasOld:
+			change = changeAdded
+			oldListing = &androidpublisher.Listing{}
+		case !hasNew:
+			change = changeRemoved
+			newListing = &androidpublisher.Listing{}
+		}
+
+		var fields []fieldChange
+		fields = appendFieldChange(fields, "title", oldListing.Title, newListing.Title)
+		fields = appendFieldChange(fields, "shortDescription", oldListing.ShortDescription, newListing.ShortDescription)
+		fields = appendFieldChange(fields, "fullDescription", oldListing.FullDescription, newListing.FullDescription)
+		fields = appendFieldChange(fields, "video", oldListing.Video, newListing.Video)
+		if change == changeUpdated && len(fields) == 0 {
+			continue
+		}
+
+		changes = append(changes, listingChange{Language: language, Change: change, Fields: fields})
+	}
+	return changes
+}
+
+func diffTesters(before, after map[string]*androidpublisher.Testers) []testersChange {
+	var changes []testersChange
+	for _, track := range sortedKeys(before, after) {
+		var oldGroups, newGroups []string
+		if testers := before[track]; testers != nil {
+			oldGroups = sortedCopy(testers.GoogleGroups)
+		}
+		if testers := after[track]; testers != nil {
+			newGroups = sortedCopy(testers.GoogleGroups)
+		}
+		if strings.Join(oldGroups, ",") == strings.Join(newGroups, ",") {
+			continue
+		}
+		changes = append(changes, testersChange{Track: track, Old: oldGroups, New: newGroups})
+	}
+	return changes
+}
+
+func diffDetails(before, after *androidpublisher.AppDetails) []fieldChange {
+	if before == nil {
+		before = &androidpublisher.AppDetails{}
+	}
+	if after == nil {
+		after = &androidpublisher.AppDetails{}
+	}
+
+	var changes []fieldChange
+	changes = appendFieldChange(changes, "contactEmail", before.ContactEmail, after.ContactEmail)
+	changes = appendFieldChange(changes, "contactPhone", before.ContactPhone, after.ContactPhone)
+	changes = appendFieldChange(changes, "contactWebsite", before.ContactWebsite, after.ContactWebsite)
+	changes = appendFieldChange(changes, "defaultLanguage", before.DefaultLanguage, after.DefaultLanguage)
+	return changes
+}
+
+func appendFieldChange(changes []fieldChange, field, oldValue, newValue string) []fieldChange {
+	if oldValue == newValue {
+		return changes
+	}
+	return append(changes, fieldChange{Field: field, Old: oldValue, New: newValue})
+}
+
+func formatFraction(fraction float64) string {
+	if fraction == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(fraction, 'f', -1, 64)
+}
+
+func formatPriority(priority int64) string {
+	if priority == 0 {
+		return ""
+	}
+	return strconv.FormatInt(priority, 10)
+}
+
+func sortedCopy(values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// sortedKeys returns the union of the keys of the given maps in ascending order.
+func sortedKeys[V any](maps ...map[string]V) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// printEditDiff prints the given diff in a human-readable form.
+func (p *Publisher) printEditDiff(diff editDiff) {
+	if diff.isEmpty() {
+		p.logger.Printf("The edit does not change anything")
+		return
+	}
+
+	for _, change := range diff.Releases {
+		p.logger.Printf("%s track: release %s (version codes: %v) %s", change.Track, releaseDisplayName(change), change.VersionCodes, change.Change)
+		for _, field := range change.Fields {
+			p.logger.Printf("    %s: %s", field.Field, formatFieldChange(field))
+		}
+		if len(change.NotesChanged) > 0 {
+			p.logger.Printf("    release notes changed: %s", strings.Join(change.NotesChanged, ", "))
+		}
+	}
+
+	for _, change := range diff.Listings {
+		p.logger.Printf("%s listing %s", change.Language, change.Change)
+		for _, field := range change.Fields {
+			p.logger.Printf("    %s: %s", field.Field, formatFieldChange(field))
+		}
+	}
+
+	for _, change := range diff.Testers {
+		p.logger.Printf("%s track testers: %v -> %v", change.Track, change.Old, change.New)
+	}
+
+	for _, field := range diff.Details {
+		p.logger.Printf("App details %s: %s", field.Field, formatFieldChange(field))
+	}
+}
+
+func releaseDisplayName(change releaseChange) string {
+	if change.Name == "" {
+		return "<unnamed>"
+	}
+	return change.Name
+}
+
+func formatFieldChange(change fieldChange) string {
+	return fmt.Sprintf("%q -> %q", change.Old, change.New)
+}
+
+// exportEditDiff writes the diff as JSON into the deploy directory. Returns its path as output.
+func (p *Publisher) exportEditDiff(deployDir string, diff editDiff) (Outputs, error) {
+	if deployDir == "" {
+		p.logger.Warnf("Deploy directory is not set, skipping the export of the dry run diff")
+		return nil, nil
+	}
+
+	content, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize diff, error: %w", err)
+	}
+
+	pth := filepath.Join(deployDir, dryRunDiffFileName)
+	if err := os.WriteFile(pth, content, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write diff to %s, error: %w", pth, err)
+	}
+	p.logger.Printf(" dry run diff exported to: %s", pth)
+	return Outputs{dryRunDiffPathKey: pth}, nil
+}
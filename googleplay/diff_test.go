@@ -0,0 +1,97 @@
+package googleplay
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+func Test_diffReleases(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []*androidpublisher.TrackRelease
+		after  []*androidpublisher.TrackRelease
+		want   []releaseChange
+	}{
+		{
+			name:   "no change",
+			before: []*androidpublisher.TrackRelease{{VersionCodes: []int64{1}, Status: releaseStatusCompleted}},
+			after:  []*androidpublisher.TrackRelease{{VersionCodes: []int64{1}, Status: releaseStatusCompleted}},
+			want:   nil,
+		},
+		{
+			name:   "release replaced",
+			before: []*androidpublisher.TrackRelease{{Name: "1.0", VersionCodes: []int64{1}, Status: releaseStatusCompleted}},
+			after:  []*androidpublisher.TrackRelease{{Name: "1.1", VersionCodes: []int64{2}, Status: releaseStatusCompleted}},
+			want: []releaseChange{
+				{
+					Track:        "beta",
+					Change:       changeAdded,
+					Name:         "1.1",
+					VersionCodes: []int64{2},
+					Fields: []fieldChange{
+						{Field: "name", Old: "", New: "1.1"},
+						{Field: "status", Old: "", New: releaseStatusCompleted},
+					},
+				},
+				{Track: "beta", Change: changeReplaced, Name: "1.0", VersionCodes: []int64{1}},
+			},
+		},
+		{
+			name: "fraction and notes changed",
+			before: []*androidpublisher.TrackRelease{{
+				VersionCodes: []int64{2, 1},
+				Status:       releaseStatusInProgress,
+				UserFraction: 0.1,
+				ReleaseNotes: []*androidpublisher.LocalizedText{{Language: "en-US", Text: "Old"}, {Language: "de-DE", Text: "Alt"}},
+			}},
+			after: []*androidpublisher.TrackRelease{{
+				VersionCodes: []int64{1, 2},
+				Status:       releaseStatusInProgress,
+				UserFraction: 0.5,
+				ReleaseNotes: []*androidpublisher.LocalizedText{{Language: "en-US", Text: "New"}, {Language: "de-DE", Text: "Alt"}},
+			}},
+			want: []releaseChange{
+				{
+					Track:        "beta",
+					Change:       changeUpdated,
+					VersionCodes: []int64{1, 2},
+					Fields:       []fieldChange{{Field: "userFraction", Old: "0.1", New: "0.5"}},
+					NotesChanged: []string{"en-US"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffReleases("beta", tt.before, tt.after)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_diffEditStates(t *testing.T) {
+	before := editState{
+		Listings: []*androidpublisher.Listing{{Language: "en-US", Title: "App"}},
+		Testers:  map[string]*androidpublisher.Testers{"alpha": {GoogleGroups: []string{"qa@example.com"}}},
+		Details:  &androidpublisher.AppDetails{ContactEmail: "old@example.com"},
+	}
+	after := editState{
+		Listings: []*androidpublisher.Listing{{Language: "en-US", Title: "App"}, {Language: "de-DE", Title: "Anwendung"}},
+		Testers:  map[string]*androidpublisher.Testers{"alpha": {GoogleGroups: []string{"qa@example.com", "beta@example.com"}}},
+		Details:  &androidpublisher.AppDetails{ContactEmail: "new@example.com"},
+	}
+
+	got := diffEditStates(before, after)
+
+	assert.Equal(t, []listingChange{
+		{Language: "de-DE", Change: changeAdded, Fields: []fieldChange{{Field: "title", Old: "", New: "Anwendung"}}},
+	}, got.Listings)
+	assert.Equal(t, []testersChange{
+		{Track: "alpha", Old: []string{"qa@example.com"}, New: []string{"beta@example.com", "qa@example.com"}},
+	}, got.Testers)
+	assert.Equal(t, []fieldChange{{Field: "contactEmail", Old: "old@example.com", New: "new@example.com"}}, got.Details)
+	assert.False(t, got.isEmpty())
+	assert.True(t, diffEditStates(before, before).isEmpty())
+}
@@ -0,0 +1,198 @@
+package googleplay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+// editCleanupTimeout limits inspecting and deleting a failed or orphaned edit. The deployment context may already be
+// done at this point, so these calls have their own timeout.
+const editCleanupTimeout = 30 * time.Second
+
+// openEditsDir is where the open edits are recorded, so that the next run can delete an edit left open by a run which
+// did not finish (for example a killed build). Every run records its edit under its own PID, so that concurrent runs of
+// the same app do not delete each other's live edit.
+var openEditsDir = filepath.Join(os.TempDir(), "google-play-deploy", "open-edits")
+
+// openEdit is the record of an edit created by a run, removed once the run committed, deleted or persisted it.
+type openEdit struct {
+	EditID    string    `json:"edit_id"`
+	PID       int       `json:"pid"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+func openEditPath(packageName string, pid int) string {
+	return filepath.Join(openEditsDir, packageName, strconv.Itoa(pid)+".json")
+}
+
+// rememberOpenEdit records the created edit until forgetOpenEdit is called.
+func (p *Publisher) rememberOpenEdit(packageName string, appEdit *androidpublisher.AppEdit) {
+	p.recordOpenEdit(packageName, os.Getpid(), appEdit)
+}
+
+// recordOpenEdit records the edit as opened by the process of the given PID.
+func (p *Publisher) recordOpenEdit(packageName string, pid int, appEdit *androidpublisher.AppEdit) {
+	record := openEdit{EditID: appEdit.Id, PID: pid, CreatedAt: time.Now().UTC(), ExpiresAt: editExpiry(appEdit)}
+	content, err := json.Marshal(record)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(openEditPath(packageName, pid)), 0700)
+	}
+	if err == nil {
+		err = os.WriteFile(openEditPath(packageName, pid), content, 0600)
+	}
+	if err != nil {
+		p.logger.Debugf("Unable to record open edit %s, error: %s", appEdit.Id, err)
+	}
+}
+
+// forgetOpenEdit removes the record of the open edit of the app created by this run.
+func (p *Publisher) forgetOpenEdit(packageName string) {
+	p.removeOpenEditRecord(openEditPath(packageName, os.Getpid()))
+}
+
+func (p *Publisher) removeOpenEditRecord(pth string) {
+	if err := os.Remove(pth); err != nil && !os.IsNotExist(err) {
+		p.logger.Debugf("Unable to remove the record of the open edit, error: %s", err)
+	}
+}
+
+// cleanUpOrphanedEdit deletes the edits previous runs of the app left open, so that they do not conflict with the new
+// edit. The edits of the runs still in progress are left alone.
+func (p *Publisher) cleanUpOrphanedEdit(service *androidpublisher.Service, packageName string) {
+	pths, err := filepath.Glob(filepath.Join(openEditsDir, packageName, "*.json"))
+	if err != nil {
+		p.logger.Debugf("Unable to list the records of open edits, error: %s", err)
+		return
+	}
+	for _, pth := range pths {
+		p.cleanUpOpenEditRecord(service, packageName, pth)
+	}
+}
+
+// cleanUpOpenEditRecord deletes the edit of the given record, unless the run which created it is still in progress.
+func (p *Publisher) cleanUpOpenEditRecord(service *androidpublisher.Service, packageName string, pth string) {
+	content, err := os.ReadFile(pth)
+	if os.IsNotExist(err) {
+		return
+	}
+
+	var record openEdit
+	if err == nil {
+		err = json.Unmarshal(content, &record)
+	}
+	if err != nil || record.EditID == "" {
+		p.logger.Warnf("Ignoring invalid record of an orphaned edit, error: %v", err)
+		p.removeOpenEditRecord(pth)
+		return
+	}
+	if processAlive(record.PID) {
+		p.logger.Debugf("Edit %s is open by a run in progress (PID %d), leaving it alone", record.EditID, record.PID)
+		return
+	}
+	defer p.removeOpenEditRecord(pth)
+
+	fmt.Println()
+	p.logger.Infof("Clean up orphaned edit")
+	p.logger.Warnf("A previous run did not finish and left edit %s open (created at %s)", record.EditID, record.CreatedAt.Format(time.RFC3339))
+	if !record.ExpiresAt.IsZero() && time.Now().After(record.ExpiresAt) {
+		p.logger.Printf(" the edit already expired")
+		return
+	}
+	if err := p.deleteEdit(service, packageName, record.EditID); err != nil {
+		p.logger.Warnf("Unable to delete orphaned edit %s, it is deleted when it expires, error: %s", record.EditID, err)
+		if expiry := record.ExpiresAt; !expiry.IsZero() {
+			p.logger.Warnf("If the new edit conflicts with it, run the Step again after %s", expiry.Format(time.RFC3339))
+		}
+		return
+	}
+	p.logger.Donef("Orphaned edit deleted")
+}
+
+// processAlive returns true if a process of the given PID is running. A process of an other user is running too,
+// signalling it is only not permitted.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// handleFailedEdit logs the state of the edit the deployment failed with, then deletes it unless keeping it was
+// requested.
+func (p *Publisher) handleFailedEdit(service *androidpublisher.Service, configs Configs, appEdit *androidpublisher.AppEdit, phase string, deployErr error) {
+	fmt.Println()
+	p.logger.Infof("Clean up failed edit")
+	if ClassifyError(deployErr).Code == FailureEditDeleted {
+		p.logger.Printf(" edit %s was deleted by Google Play, nothing to clean up", appEdit.Id)
+		return
+	}
+
+	p.logEditState(service, configs, appEdit, phase)
+	if configs.KeepFailedEdit {
+		p.logger.Warnf("Keeping edit %s as requested, it is deleted by Google Play when it expires", appEdit.Id)
+		return
+	}
+	if err := p.deleteEdit(service, configs.PackageName, appEdit.Id); err != nil {
+		p.logger.Warnf("Failed to delete edit %s, error: %s", appEdit.Id, err)
+		return
+	}
+	p.logger.Donef("Failed edit deleted")
+}
+
+// logEditState logs the edit and the releases of the configured track in it.
+func (p *Publisher) logEditState(service *androidpublisher.Service, configs Configs, appEdit *androidpublisher.AppEdit, phase string) {
+	p.logger.Printf(" editID: %s", appEdit.Id)
+	p.logger.Printf(" failed phase: %s", phase)
+	if expiry := editExpiry(appEdit); !expiry.IsZero() {
+		p.logger.Printf(" expires at: %s", expiry.Format(time.RFC3339))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), editCleanupTimeout)
+	defer cancel()
+	track, err := androidpublisher.NewEditsTracksService(service).Get(configs.PackageName, appEdit.Id, configs.Track).Context(ctx).Do()
+	if err != nil {
+		p.logger.Debugf("Unable to fetch the %s track of the edit, error: %s", configs.Track, err)
+		return
+	}
+	p.logger.Printf(" releases of the %s track in the edit:", configs.Track)
+	for _, release := range track.Releases {
+		p.logger.Printf(" - name: %s, status: %s, version codes: %v, user fraction: %v", release.Name, release.Status, release.VersionCodes, release.UserFraction)
+	}
+}
+
+// deleteEdit deletes the given edit.
+func (p *Publisher) deleteEdit(service *androidpublisher.Service, packageName string, editID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), editCleanupTimeout)
+	defer cancel()
+
+	p.logger.Printf("Deleting edit: %s", editID)
+	if err := androidpublisher.NewEditsService(service).Delete(packageName, editID).Context(ctx).Do(); err != nil {
+		return err
+	}
+	p.logger.Printf(" edit deleted")
+	return nil
+}
+
+// editExpiry returns when Google Play deletes the uncommitted edit, or zero if unknown.
+func editExpiry(appEdit *androidpublisher.AppEdit) time.Time {
+	seconds, err := strconv.ParseInt(appEdit.ExpiryTimeSeconds, 10, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0).UTC()
+}
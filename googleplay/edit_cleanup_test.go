@@ -0,0 +1,83 @@
+package googleplay
+
+import (
+	"context"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+func TestPublisher_executeEdit_failedEdit(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+
+	tests := []struct {
+		name           string
+		keepFailedEdit bool
+		wantOpenEdits  int
+	}{
+		{name: "deleted", wantOpenEdits: 0},
+		{name: "kept for debugging", keepFailedEdit: true, wantOpenEdits: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs := Configs{PackageName: packageName, AppPath: writeBundles(t, "1"), Track: "qa", KeepFailedEdit: tt.keepFailedEdit, Logger: log.NewLogger()}
+			server, publisher, service := newEmulatedPublisher(t, configs)
+
+			err := publisher.executeEdit(context.Background(), service, configs, Outputs{}, false, false)
+			require.Error(t, err)
+			assert.Equal(t, FailureTrackNotFound, ClassifyError(err).Code)
+			assert.Equal(t, tt.wantOpenEdits, server.OpenEdits(packageName))
+			assert.NoFileExists(t, openEditPath(packageName, os.Getpid()))
+		})
+	}
+}
+
+// orphanPID is a PID no process runs with, as it is above the PID limit of the systems.
+const orphanPID = math.MaxInt32
+
+func TestPublisher_executeEdit_cleansUpOrphanedEdit(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+
+	configs := Configs{PackageName: packageName, AppPath: writeBundles(t, "1"), Track: "beta", Logger: log.NewLogger()}
+	server, publisher, service := newEmulatedPublisher(t, configs)
+
+	orphan, err := androidpublisher.NewEditsService(service).Insert(packageName, &androidpublisher.AppEdit{}).Do()
+	require.NoError(t, err)
+	publisher.recordOpenEdit(packageName, orphanPID, orphan)
+	require.Equal(t, 1, server.OpenEdits(packageName))
+
+	require.NoError(t, publisher.executeEdit(context.Background(), service, configs, Outputs{}, false, false))
+	assert.Equal(t, 0, server.OpenEdits(packageName))
+	assert.Equal(t, 1, server.Commits(packageName))
+	assert.NoFileExists(t, openEditPath(packageName, orphanPID))
+	assert.NoFileExists(t, openEditPath(packageName, os.Getpid()))
+}
+
+func TestPublisher_cleanUpOrphanedEdit_keepsEditOfRunInProgress(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+
+	configs := Configs{PackageName: packageName, Logger: log.NewLogger()}
+	server, publisher, service := newEmulatedPublisher(t, configs)
+
+	// The run in progress is this process.
+	editsService := androidpublisher.NewEditsService(service)
+	orphan, err := editsService.Insert(packageName, &androidpublisher.AppEdit{}).Do()
+	require.NoError(t, err)
+	publisher.recordOpenEdit(packageName, orphanPID, orphan)
+	inProgress, err := editsService.Insert(packageName, &androidpublisher.AppEdit{}).Do()
+	require.NoError(t, err)
+	publisher.recordOpenEdit(packageName, os.Getpid(), inProgress)
+
+	publisher.cleanUpOrphanedEdit(service, packageName)
+	assert.Equal(t, 1, server.OpenEdits(packageName))
+	assert.NoFileExists(t, openEditPath(packageName, orphanPID))
+	assert.FileExists(t, openEditPath(packageName, os.Getpid()))
+
+	_, err = editsService.Get(packageName, inProgress.Id).Do()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,45 @@
+package googleplay
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const editAttemptsKey = "GOOGLE_PLAY_EDIT_ATTEMPTS"
+
+// editRetryWait is the wait before running the edit again, doubled for every further attempt.
+var editRetryWait = 30 * time.Second
+
+// isRetryableEditFailure returns true if the edit failed with a transient server error or because it was deleted or
+// expired, so running the whole edit flow again with a new edit may succeed.
+func isRetryableEditFailure(err error) bool {
+	switch ClassifyError(err).Code {
+	case FailureInternalServerError, FailureEditDeleted:
+		return true
+	default:
+		return false
+	}
+}
+
+// reportEditAttempts prints the attempts of the edit if it was run more than once. Returns the attempts as JSON output.
+func (p *Publisher) reportEditAttempts(attempts []*editAttempt) Outputs {
+	if len(attempts) > 1 {
+		fmt.Println()
+		p.logger.Infof("Edit attempts")
+		for _, attempt := range attempts {
+			if attempt.Outcome == outcomeFailed {
+				p.logger.Printf("- attempt %d (edit %s): %s (%s)", attempt.Number, attempt.EditID, attempt.Outcome, attempt.FailureReason)
+			} else {
+				p.logger.Printf("- attempt %d (edit %s): %s", attempt.Number, attempt.EditID, attempt.Outcome)
+			}
+		}
+	}
+
+	content, err := json.Marshal(attempts)
+	if err != nil {
+		p.logger.Warnf("Unable to serialize edit attempts, error: %s", err)
+		return nil
+	}
+	return Outputs{editAttemptsKey: string(content)}
+}
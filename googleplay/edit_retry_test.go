@@ -0,0 +1,165 @@
+package googleplay
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-google-play-deploy/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func serverErrorResponse(req *http.Request) *http.Response {
+	recorder := httptest.NewRecorder()
+	googleErrorResponse(recorder, http.StatusInternalServerError, "backendError")
+	resp := recorder.Result()
+	resp.Request = req
+	return resp
+}
+
+func TestPublisher_executeEdit_retries(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	wait := editRetryWait
+	editRetryWait = 0
+	t.Cleanup(func() { editRetryWait = wait })
+	isCommit := func(req *http.Request) bool {
+		return strings.HasSuffix(req.URL.Path, ":commit")
+	}
+
+	tests := []struct {
+		name string
+		// failCommit returns true if the given commit (counted from 1) fails.
+		failCommit func(commit int32) bool
+		// failGeneratedApks makes the listing of the generated APKs fail, after the edit was committed.
+		failGeneratedApks bool
+		editRetries       int
+		wantErrCode       FailureCode
+		wantAttempts      []string
+		wantCommits       int
+		wantUploads       int32
+	}{
+		{
+			name: "server error retried with a new edit",
+			failCommit: func(commit int32) bool {
+				return commit == 1
+			},
+			editRetries:  2,
+			wantAttempts: []string{outcomeFailed, outcomeSucceeded},
+			wantCommits:  1,
+			wantUploads:  2,
+		},
+		{
+			name: "committed edit not retried",
+			failCommit: func(int32) bool {
+				return false
+			},
+			failGeneratedApks: true,
+			editRetries:       2,
+			wantErrCode:       FailureInternalServerError,
+			wantAttempts:      []string{outcomeFailed},
+			wantCommits:       1,
+			wantUploads:       1,
+		},
+		{
+			name: "retries exhausted",
+			failCommit: func(int32) bool {
+				return true
+			},
+			editRetries:  1,
+			wantErrCode:  FailureInternalServerError,
+			wantAttempts: []string{outcomeFailed, outcomeFailed},
+			wantUploads:  2,
+		},
+		{
+			name: "retries disabled",
+			failCommit: func(int32) bool {
+				return true
+			},
+			wantErrCode:  FailureInternalServerError,
+			wantAttempts: []string{outcomeFailed},
+			wantUploads:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := emulator.NewServer()
+			server.AddApp(packageName)
+
+			var commits, uploads int32
+			client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				if strings.Contains(req.URL.Path, "/upload/") && strings.HasSuffix(req.URL.Path, "/bundles") {
+					atomic.AddInt32(&uploads, 1)
+				}
+				if isCommit(req) && tt.failCommit(atomic.AddInt32(&commits, 1)) {
+					return serverErrorResponse(req), nil
+				}
+				if tt.failGeneratedApks && strings.Contains(req.URL.Path, "/generatedApks/") {
+					return serverErrorResponse(req), nil
+				}
+				return server.RoundTrip(req)
+			})}
+
+			deployDir := t.TempDir()
+			configs := Configs{
+				PackageName:      packageName,
+				AppPath:          writeBundles(t, "1"),
+				Track:            "beta",
+				EditRetries:      tt.editRetries,
+				DeployDir:        deployDir,
+				DeploymentReport: deploymentReportJSON,
+				Logger:           log.NewLogger(),
+			}
+			if tt.failGeneratedApks {
+				configs.GeneratedApksDownload = generatedApksDownloadUniversal
+			}
+			publisher := NewPublisher(log.NewLogger())
+			service, err := publisher.createService(context.Background(), configs, client)
+			require.NoError(t, err)
+
+			err = publisher.executeEdit(context.Background(), service, configs, Outputs{}, false, false)
+			if tt.wantErrCode != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantErrCode, ClassifyError(err).Code)
+			} else {
+				require.NoError(t, err)
+				track := server.Track(packageName, "beta")
+				require.NotNil(t, track)
+				assert.Equal(t, []*androidpublisher.TrackRelease{{Status: releaseStatusCompleted, VersionCodes: []int64{1}}}, track.Releases)
+			}
+			assert.Equal(t, tt.wantCommits, server.Commits(packageName))
+			assert.Equal(t, tt.wantUploads, atomic.LoadInt32(&uploads))
+
+			content, err := os.ReadFile(filepath.Join(deployDir, deploymentReportFileName))
+			require.NoError(t, err)
+			var report deploymentReport
+			require.NoError(t, json.Unmarshal(content, &report))
+			var attempts []string
+			for _, attempt := range report.Attempts {
+				attempts = append(attempts, attempt.Outcome)
+			}
+			assert.Equal(t, tt.wantAttempts, attempts)
+		})
+	}
+}
+
+func Test_isRetryableEditFailure(t *testing.T) {
+	assert.True(t, isRetryableEditFailure(apiErrorOf(http.StatusInternalServerError, "Internal error encountered.", "backendError")))
+	assert.True(t, isRetryableEditFailure(apiErrorOf(http.StatusBadRequest, "This Edit has been deleted.", "editDeleted")))
+	assert.False(t, isRetryableEditFailure(apiErrorOf(http.StatusForbidden, "APK specifies a version code that has already been used.", "forbidden")))
+	assert.False(t, isRetryableEditFailure(context.DeadlineExceeded))
+}
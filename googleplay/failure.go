@@ -0,0 +1,147 @@
+package googleplay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	failureReasonKey  = "FAILURE_REASON"
+	failureMessageKey = "FAILURE_MESSAGE"
+)
+
+const changesNotSentForReviewMessage = "Changes cannot be sent for review automatically. Please set the query parameter changesNotSentForReview to true"
+const bundleInstallationWarning = "The installation of the app bundle may be too large " +
+	"and trigger user warning on some devices, and this needs to be explicitly acknowledged in the request."
+
+// FailureCode is a stable, machine-readable classification of why the deployment failed, exported as FAILURE_REASON.
+type FailureCode string
+
+// The codes of the failures the deployment can fail with.
+const (
+	FailureVersionCodeConflict       FailureCode = "VERSION_CODE_CONFLICT"
+	FailurePermissionDenied          FailureCode = "PERMISSION_DENIED"
+	FailurePackageNotFound           FailureCode = "PACKAGE_NOT_FOUND"
+	FailureTrackNotFound             FailureCode = "TRACK_NOT_FOUND"
+	FailureTooManyCompletedReleases  FailureCode = "TOO_MANY_COMPLETED_RELEASES"
+	FailureBundleInstallationWarning FailureCode = "BUNDLE_INSTALLATION_WARNING"
+	FailureQuotaExceeded             FailureCode = "QUOTA_EXCEEDED"
+	FailureChangesNotSentForReview   FailureCode = "CHANGES_NOT_SENT_FOR_REVIEW"
+	FailureEditDeleted               FailureCode = "EDIT_DELETED"
+	FailureInternalServerError       FailureCode = "INTERNAL_SERVER_ERROR"
+	FailureTimeout                   FailureCode = "TIMEOUT"
+	FailureCancelled                 FailureCode = "CANCELLED"
+	FailureAPIError                  FailureCode = "API_ERROR"
+	FailureUnknown                   FailureCode = "UNKNOWN"
+)
+
+// Failure is the classification of an error of the deployment.
+type Failure struct {
+	Code        FailureCode
+	Message     string
+	Remediation string
+	// APIError is the Google Play API error the failure was classified by, if any.
+	APIError *googleapi.Error
+}
+
+var failureRemediations = map[FailureCode]string{
+	FailureVersionCodeConflict:       "The version code is already used by a previously uploaded app. Increase the version code of the app and build it again.",
+	FailurePermissionDenied:          "Check that the service account has access to the app in the Google Play Console (Users and permissions), with the permissions to release to the track.",
+	FailurePackageNotFound:           "Check the package name, and that the first version of the app was uploaded manually in the Google Play Console.",
+	FailureTrackNotFound:             "Check the track name: use one of the tracks listed by the Step, or create the custom track in the Google Play Console.",
+	FailureTooManyCompletedReleases:  "The track can only have one completed release. Set a user fraction or the inProgress status to start a staged rollout instead.",
+	FailureBundleInstallationWarning: "To acknowledge this warning, set the Acknowledge Bundle Installation Warning (ack_bundle_installation_warning) input to true.",
+	FailureQuotaExceeded:             "The Google Play Developer API quota was exceeded. Deploy less often or fewer apps at once, or request a higher quota from Google.",
+	FailureChangesNotSentForReview:   "Set the Retry changes without sending to review (retry_without_sending_to_review) input to true, then send the changes for review from the Google Play Console.",
+	FailureEditDeleted:               "The edit was deleted or expired, most likely because an other deployment of the app was committed meanwhile. Run the Step again.",
+	FailureInternalServerError:       "Google Play API responded with an unknown error. Create the release manually in the Google Play Console, as the UI can present the underlying error in certain cases.",
+	FailureTimeout:                   "Increase the deployment timeout (deployment_timeout) or the API call timeout (call_timeout) input.",
+}
+
+// ClassifyError classifies the given error by the Google Play API error it wraps, if any.
+func ClassifyError(err error) Failure {
+	f := Failure{Code: FailureUnknown, Message: err.Error()}
+
+	var apiErr *googleapi.Error
+	var retrieveErr *oauth2.RetrieveError
+	var trackErr unknownTrackError
+	switch {
+	case errors.As(err, &trackErr):
+		f.Code = FailureTrackNotFound
+	case errors.As(err, &apiErr):
+		f.APIError = apiErr
+		f.Code = classifyAPIError(apiErr)
+	case errors.As(err, &retrieveErr):
+		f.Code = FailurePermissionDenied
+	case errors.Is(err, context.DeadlineExceeded):
+		f.Code = FailureTimeout
+	case errors.Is(err, context.Canceled):
+		f.Code = FailureCancelled
+	}
+
+	f.Remediation = failureRemediations[f.Code]
+	return f
+}
+
+// classifyAPIError classifies a Google Play API error by its reasons, status code and message.
+func classifyAPIError(apiErr *googleapi.Error) FailureCode {
+	reasons := map[string]bool{}
+	for _, item := range apiErr.Errors {
+		reasons[item.Reason] = true
+	}
+	message := apiErr.Message
+	lowerMessage := strings.ToLower(message)
+
+	switch {
+	case strings.Contains(message, changesNotSentForReviewMessage):
+		return FailureChangesNotSentForReview
+	case strings.Contains(message, bundleInstallationWarning):
+		return FailureBundleInstallationWarning
+	case reasons["apkUpgradeVersionConflict"] || strings.Contains(lowerMessage, "version code that has already been used"):
+		return FailureVersionCodeConflict
+	case reasons["releasesTooManyCompletedReleases"]:
+		return FailureTooManyCompletedReleases
+	case reasons["editDeleted"] || reasons["editExpired"] || strings.Contains(message, "This Edit has been deleted"):
+		return FailureEditDeleted
+	case reasons["applicationNotFound"] || strings.Contains(lowerMessage, "package not found"):
+		return FailurePackageNotFound
+	case apiErr.Code == http.StatusNotFound && strings.Contains(lowerMessage, "track"):
+		return FailureTrackNotFound
+	case apiErr.Code == http.StatusTooManyRequests || reasons["rateLimitExceeded"] || reasons["userRateLimitExceeded"] || reasons["quotaExceeded"] || reasons["dailyLimitExceeded"]:
+		return FailureQuotaExceeded
+	case apiErr.Code == http.StatusUnauthorized || apiErr.Code == http.StatusForbidden:
+		return FailurePermissionDenied
+	case apiErr.Code >= http.StatusInternalServerError:
+		return FailureInternalServerError
+	default:
+		return FailureAPIError
+	}
+}
+
+// Outputs returns the outputs describing the failure: its code as FAILURE_REASON and its message as FAILURE_MESSAGE.
+func (f Failure) Outputs() Outputs {
+	return Outputs{
+		failureReasonKey:  string(f.Code),
+		failureMessageKey: f.Message,
+	}
+}
+
+// ReportFailure logs the error with its remediation, and sends the outputs of the failure to the output sink of the
+// options. Returns the classification.
+func (p *Publisher) ReportFailure(err error) Failure {
+	f := ClassifyError(err)
+
+	p.logger.Errorf("%s", f.Message)
+	if f.Remediation != "" {
+		p.logger.Warnf("Suggestion: %s", f.Remediation)
+	}
+	p.sendOutputs(f.Outputs())
+	p.logger.Printf("Failure reason: %s", f.Code)
+
+	return f
+}
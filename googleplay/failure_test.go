@@ -0,0 +1,123 @@
+package googleplay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+func apiErrorOf(code int, message, reason string) error {
+	apiErr := &googleapi.Error{Code: code, Message: message}
+	if reason != "" {
+		apiErr.Errors = []googleapi.ErrorItem{{Reason: reason, Message: message}}
+	}
+	return fmt.Errorf("Failed to commit edit, error: %w", apiErr)
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want FailureCode
+	}{
+		{
+			name: "version code already used",
+			err:  apiErrorOf(http.StatusForbidden, "APK specifies a version code that has already been used.", "forbidden"),
+			want: FailureVersionCodeConflict,
+		},
+		{
+			name: "artifact already uploaded",
+			err:  apiErrorOf(http.StatusForbidden, "This artifact was already uploaded with version code 1.", "apkUpgradeVersionConflict"),
+			want: FailureVersionCodeConflict,
+		},
+		{
+			name: "permission denied",
+			err:  apiErrorOf(http.StatusForbidden, "The caller does not have permission", "forbidden"),
+			want: FailurePermissionDenied,
+		},
+		{
+			name: "token request rejected",
+			err:  fmt.Errorf("failed to create service: %w", &oauth2.RetrieveError{Response: &http.Response{StatusCode: http.StatusBadRequest}}),
+			want: FailurePermissionDenied,
+		},
+		{
+			name: "package not found",
+			err:  apiErrorOf(http.StatusNotFound, "Package not found: io.bitrise.sample.", "applicationNotFound"),
+			want: FailurePackageNotFound,
+		},
+		{
+			name: "track not found",
+			err:  apiErrorOf(http.StatusNotFound, "Track not found: qa.", "notFound"),
+			want: FailureTrackNotFound,
+		},
+		{
+			name: "too many completed releases",
+			err:  apiErrorOf(http.StatusBadRequest, "Too many completed releases specified.", "releasesTooManyCompletedReleases"),
+			want: FailureTooManyCompletedReleases,
+		},
+		{
+			name: "bundle installation warning",
+			err:  apiErrorOf(http.StatusForbidden, bundleInstallationWarning, "forbidden"),
+			want: FailureBundleInstallationWarning,
+		},
+		{
+			name: "quota exceeded",
+			err:  apiErrorOf(http.StatusTooManyRequests, "Quota exceeded", "rateLimitExceeded"),
+			want: FailureQuotaExceeded,
+		},
+		{
+			name: "daily limit exceeded",
+			err:  apiErrorOf(http.StatusForbidden, "Daily Limit Exceeded", "dailyLimitExceeded"),
+			want: FailureQuotaExceeded,
+		},
+		{
+			name: "changes not sent for review",
+			err:  apiErrorOf(http.StatusBadRequest, changesNotSentForReviewMessage+". Once committed, the changes in this edit can be sent for review from the Google Play Console UI.", "badRequest"),
+			want: FailureChangesNotSentForReview,
+		},
+		{
+			name: "edit deleted",
+			err:  apiErrorOf(http.StatusBadRequest, "This Edit has been deleted.", "editDeleted"),
+			want: FailureEditDeleted,
+		},
+		{
+			name: "internal server error",
+			err:  apiErrorOf(http.StatusInternalServerError, "Internal error encountered.", "backendError"),
+			want: FailureInternalServerError,
+		},
+		{
+			name: "other API error",
+			err:  apiErrorOf(http.StatusBadRequest, "Invalid request", "badRequest"),
+			want: FailureAPIError,
+		},
+		{
+			name: "timeout",
+			err:  fmt.Errorf("Failed to upload application(s): %w", context.DeadlineExceeded),
+			want: FailureTimeout,
+		},
+		{
+			name: "cancelled",
+			err:  fmt.Errorf("Failed to upload application(s): %w", context.Canceled),
+			want: FailureCancelled,
+		},
+		{
+			name: "unknown",
+			err:  errors.New("failed to open app"),
+			want: FailureUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyError(tt.err)
+			assert.Equal(t, tt.want, got.Code)
+			assert.Equal(t, tt.err.Error(), got.Message)
+			assert.Equal(t, failureRemediations[tt.want], got.Remediation)
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package googleplay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+const (
+	generatedApksDownloadNone      = "none"
+	generatedApksDownloadUniversal = "universal"
+	generatedApksDownloadSplits    = "splits"
+	generatedApksDownloadAll       = "all"
+)
+
+const (
+	universalApkPathListKey = "GOOGLE_PLAY_UNIVERSAL_APK_PATH_LIST"
+	splitApkPathListKey     = "GOOGLE_PLAY_SPLIT_APK_PATH_LIST"
+)
+
+// Generated APKs are not always available right after the edit is committed.
+const (
+	generatedApksListAttempts     = 6
+	generatedApksListWaitInterval = 10 * time.Second
+)
+
+// downloadGeneratedApks downloads the APKs Google Play generated from the uploaded bundles into the deploy directory.
+// Returns their paths as outputs.
+func (p *Publisher) downloadGeneratedApks(ctx context.Context, configs Configs, service *androidpublisher.Service, versionCodes []int64) (Outputs, error) {
+	includeUniversal := configs.GeneratedApksDownload == generatedApksDownloadUniversal || configs.GeneratedApksDownload == generatedApksDownloadAll
+	includeSplits := configs.GeneratedApksDownload == generatedApksDownloadSplits || configs.GeneratedApksDownload == generatedApksDownloadAll
+
+	generatedApksService := androidpublisher.NewGeneratedapksService(service)
+	var universalPaths, splitPaths []string
+	for _, versionCode := range versionCodes {
+		generatedApks, err := p.listGeneratedApks(ctx, generatedApksService, configs.PackageName, versionCode)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, perSigningKey := range generatedApks {
+			dir := filepath.Join(configs.DeployDir, "generated-apks", strconv.FormatInt(versionCode, 10))
+			if len(generatedApks) > 1 {
+				dir = filepath.Join(dir, shortHash(perSigningKey.CertificateSha256Hash))
+			}
+
+			if includeUniversal && perSigningKey.GeneratedUniversalApk != nil {
+				pth := filepath.Join(dir, "universal.apk")
+				if err := p.downloadGeneratedApk(ctx, generatedApksService, configs.PackageName, versionCode, perSigningKey.GeneratedUniversalApk.DownloadId, pth); err != nil {
+					return nil, err
+				}
+				universalPaths = append(universalPaths, pth)
+			}
+
+			if includeSplits {
+				for _, split := range perSigningKey.GeneratedSplitApks {
+					pth := filepath.Join(dir, "splits", splitApkFileName(split))
+					if err := p.downloadGeneratedApk(ctx, generatedApksService, configs.PackageName, versionCode, split.DownloadId, pth); err != nil {
+						return nil, err
+					}
+					splitPaths = append(splitPaths, pth)
+				}
+			}
+		}
+	}
+
+	if includeUniversal && len(universalPaths) == 0 {
+		p.logger.Warnf("No universal APK was generated for version codes: %v", versionCodes)
+	}
+
+	return Outputs{
+		universalApkPathListKey: strings.Join(universalPaths, "|"),
+		splitApkPathListKey:     strings.Join(splitPaths, "|"),
+	}, nil
+}
+
+// listGeneratedApks lists the generated APKs of the given version code, waiting for them to become available.
+func (p *Publisher) listGeneratedApks(ctx context.Context, generatedApksService *androidpublisher.GeneratedapksService, packageName string, versionCode int64) ([]*androidpublisher.GeneratedApksPerSigningKey, error) {
+	for attempt := 1; ; attempt++ {
+		callCtx, cancel := callContext(ctx)
+		resp, err := generatedApksService.List(packageName, versionCode).Context(callCtx).Do()
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list generated APKs of version code %d, error: %w", versionCode, err)
+		}
+		if len(resp.GeneratedApks) > 0 || attempt == generatedApksListAttempts {
+			p.logger.Printf(" generated APKs found for %d signing key(s) of version code %d", len(resp.GeneratedApks), versionCode)
+			return resp.GeneratedApks, nil
+		}
+
+		p.logger.Debugf("Generated APKs of version code %d are not available yet, retrying in %s", versionCode, generatedApksListWaitInterval)
+		if err := sleepContext(ctx, generatedApksListWaitInterval); err != nil {
+			return nil, fmt.Errorf("failed to wait for generated APKs of version code %d, error: %w", versionCode, err)
+		}
+	}
+}
+
+// downloadGeneratedApk downloads a single generated APK to the given path.
+func (p *Publisher) downloadGeneratedApk(ctx context.Context, generatedApksService *androidpublisher.GeneratedapksService, packageName string, versionCode int64, downloadID string, pth string) error {
+	p.logger.Debugf("Downloading generated APK %s of version code %d to %s", downloadID, versionCode, pth)
+	// The call context is kept until the body is written, as it also limits reading the body.
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	resp, err := generatedApksService.Download(packageName, versionCode, downloadID).Context(callCtx).Download()
+	if err != nil {
+		return fmt.Errorf("failed to download generated APK of version code %d, error: %w", versionCode, err)
+	}
+
+	if err := p.writeResponseToFile(resp, pth); err != nil {
+		return err
+	}
+	p.logger.Printf(" downloaded: %s", pth)
+	return nil
+}
+
+// writeResponseToFile writes the body of the given response to the given path, creating its parent directories.
+func (p *Publisher) writeResponseToFile(resp *http.Response, pth string) error {
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			p.logger.Warnf("Failed to close response body, error: %s", err)
+		}
+	}()
+
+	if err := os.MkdirAll(filepath.Dir(pth), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s, error: %w", pth, err)
+	}
+
+	file, err := os.Create(pth)
+	if err != nil {
+		return fmt.Errorf("failed to create %s, error: %w", pth, err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			p.logger.Warnf("Failed to close %s, error: %s", pth, err)
+		}
+	}()
+
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		return fmt.Errorf("failed to write %s, error: %w", pth, err)
+	}
+	return nil
+}
+
+// splitApkFileName returns a file name unique within the splits of a signing key.
+// Example: "1-base-master.apk", "1-base-config.arm64_v8a.apk".
+func splitApkFileName(split *androidpublisher.GeneratedSplitApk) string {
+	splitID := split.SplitId
+	if splitID == "" {
+		splitID = "master"
+	}
+	return fmt.Sprintf("%d-%s-%s.apk", split.VariantId, split.ModuleName, splitID)
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
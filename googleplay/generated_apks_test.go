@@ -0,0 +1,118 @@
+package googleplay
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-google-play-deploy/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+func TestPublisher_executeEdit_downloadsGeneratedApks(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	tests := []struct {
+		name          string
+		download      string
+		wantUniversal []string
+		wantSplits    []string
+	}{
+		{
+			name:          "universal",
+			download:      generatedApksDownloadUniversal,
+			wantUniversal: []string{"universal.apk"},
+		},
+		{
+			name:       "splits",
+			download:   generatedApksDownloadSplits,
+			wantSplits: []string{"splits/1-base-master.apk", "splits/1-base-config.arm64_v8a.apk"},
+		},
+		{
+			name:          "all",
+			download:      generatedApksDownloadAll,
+			wantUniversal: []string{"universal.apk"},
+			wantSplits:    []string{"splits/1-base-master.apk", "splits/1-base-config.arm64_v8a.apk"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployDir := t.TempDir()
+			configs := Configs{
+				PackageName:           packageName,
+				AppPath:               writeBundles(t, "2"),
+				Track:                 "beta",
+				GeneratedApksDownload: tt.download,
+				DeployDir:             deployDir,
+				Logger:                log.NewLogger(),
+			}
+			_, publisher, service := newEmulatedPublisher(t, configs)
+
+			outputs := Outputs{}
+			require.NoError(t, publisher.executeEdit(context.Background(), service, configs, outputs, false, false))
+
+			dir := filepath.Join(deployDir, "generated-apks", "2")
+			assertDownloaded := func(files []string, key string) {
+				var pths []string
+				for _, file := range files {
+					pth := filepath.Join(dir, file)
+					content, err := os.ReadFile(pth)
+					require.NoError(t, err)
+					assert.True(t, strings.HasPrefix(string(content), "generated APK "), pth)
+					pths = append(pths, pth)
+				}
+				assert.Equal(t, strings.Join(pths, "|"), outputs[key])
+			}
+			assertDownloaded(tt.wantUniversal, universalApkPathListKey)
+			assertDownloaded(tt.wantSplits, splitApkPathListKey)
+
+			if len(tt.wantUniversal) > 0 {
+				content, err := os.ReadFile(filepath.Join(dir, "universal.apk"))
+				require.NoError(t, err)
+				assert.Equal(t, emulator.GeneratedApk("universal-2"), content)
+			} else {
+				assert.NoFileExists(t, filepath.Join(dir, "universal.apk"))
+			}
+		})
+	}
+}
+
+func Test_splitApkFileName(t *testing.T) {
+	tests := []struct {
+		name  string
+		split *androidpublisher.GeneratedSplitApk
+		want  string
+	}{
+		{"master split", &androidpublisher.GeneratedSplitApk{VariantId: 1, ModuleName: "base"}, "1-base-master.apk"},
+		{"config split", &androidpublisher.GeneratedSplitApk{VariantId: 2, ModuleName: "base", SplitId: "config.arm64_v8a"}, "2-base-config.arm64_v8a.apk"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, splitApkFileName(tt.split))
+		})
+	}
+}
+
+func TestConfigs_shouldDownloadGeneratedApks(t *testing.T) {
+	tests := []struct {
+		name     string
+		download string
+		appPath  string
+		want     bool
+	}{
+		{"not requested", generatedApksDownloadNone, "app.aab", false},
+		{"empty", "", "app.aab", false},
+		{"bundle", generatedApksDownloadUniversal, "app.aab", true},
+		{"apk", generatedApksDownloadAll, "app.apk", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Configs{GeneratedApksDownload: tt.download, AppPath: tt.appPath, Logger: log.NewLogger()}
+			assert.Equal(t, tt.want, c.shouldDownloadGeneratedApks())
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package googleplay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// iamCredentialsEndpoint is the base URL of the IAM Service Account Credentials API.
+var iamCredentialsEndpoint = "https://iamcredentials.googleapis.com"
+
+// impersonatedTokenSource generates access tokens of the target service account, authenticating with the source
+// token source. The source credentials need the Service Account Token Creator role on the target service account.
+type impersonatedTokenSource struct {
+	ctx                  context.Context
+	source               oauth2.TokenSource
+	targetServiceAccount string
+	scopes               []string
+}
+
+type generateAccessTokenRequest struct {
+	Scope    []string `json:"scope"`
+	Lifetime string   `json:"lifetime"`
+}
+
+type generateAccessTokenResponse struct {
+	AccessToken string `json:"accessToken"`
+	ExpireTime  string `json:"expireTime"`
+}
+
+// Token implements oauth2.TokenSource.
+func (ts impersonatedTokenSource) Token() (*oauth2.Token, error) {
+	body, err := json.Marshal(generateAccessTokenRequest{Scope: ts.scopes, Lifetime: "3600s"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create impersonation request, error: %s", err)
+	}
+
+	generateURL := fmt.Sprintf("%s/v1/projects/-/serviceAccounts/%s:generateAccessToken", iamCredentialsEndpoint, url.PathEscape(ts.targetServiceAccount))
+	req, err := http.NewRequestWithContext(ts.ctx, http.MethodPost, generateURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create impersonation request, error: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := oauth2.NewClient(ts.ctx, ts.source).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to impersonate service account %s, error: %s", ts.targetServiceAccount, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read impersonation response, error: %s", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to impersonate service account %s, status: %d, response: %s", ts.targetServiceAccount, resp.StatusCode, respBody)
+	}
+
+	var token generateAccessTokenResponse
+	if err := json.Unmarshal(respBody, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse impersonation response, error: %s", err)
+	}
+	expiry, err := time.Parse(time.RFC3339, token.ExpireTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse impersonated token expiry (%s), error: %s", token.ExpireTime, err)
+	}
+
+	return &oauth2.Token{
+		AccessToken: token.AccessToken,
+		TokenType:   "Bearer",
+		Expiry:      expiry,
+	}, nil
+}
@@ -0,0 +1,60 @@
+package googleplay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-steputils/stepconf"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_createHTTPClient_impersonation(t *testing.T) {
+	sts := newSTSServer(t, "oidc-token", "source-access-token")
+
+	var requestedScopes []string
+	iam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/projects/-/serviceAccounts/deployer@example.iam.gserviceaccount.com:generateAccessToken" ||
+			r.Header.Get("Authorization") != "Bearer source-access-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var req generateAccessTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requestedScopes = req.Scope
+		_, _ = fmt.Fprintf(w, `{"accessToken": "impersonated-access-token", "expireTime": "%s"}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+	}))
+	defer iam.Close()
+
+	originalEndpoint := iamCredentialsEndpoint
+	iamCredentialsEndpoint = iam.URL
+	defer func() { iamCredentialsEndpoint = originalEndpoint }()
+
+	var authorization string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer api.Close()
+
+	keyPth := writeExternalAccountKey(t, sts.URL, "oidc-token")
+
+	client, err := NewPublisher(log.NewLogger()).createHTTPClient(Configs{
+		JSONKeyPath:               stepconf.Secret("file://" + keyPth),
+		ImpersonateServiceAccount: "deployer@example.iam.gserviceaccount.com",
+	})
+	require.NoError(t, err)
+
+	resp, err := client.Get(api.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "Bearer impersonated-access-token", authorization)
+	assert.Equal(t, []string{"https://www.googleapis.com/auth/androidpublisher"}, requestedScopes)
+}
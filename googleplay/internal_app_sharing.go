@@ -0,0 +1,79 @@
+package googleplay
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/api/androidpublisher/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	internalAppSharingDownloadURLKey            = "GOOGLE_PLAY_INTERNAL_APP_SHARING_DOWNLOAD_URL"
+	internalAppSharingCertificateFingerprintKey = "GOOGLE_PLAY_INTERNAL_APP_SHARING_CERTIFICATE_FINGERPRINT"
+	internalAppSharingSHA256Key                 = "GOOGLE_PLAY_INTERNAL_APP_SHARING_SHA256"
+)
+
+// uploadToInternalAppSharing uploads every application file (apk or aab) to internal app sharing. Returns the download
+// URL, certificate fingerprint and SHA-256 of the uploaded artifacts as outputs.
+func (p *Publisher) uploadToInternalAppSharing(ctx context.Context, configs Configs, service *androidpublisher.Service) (Outputs, error) {
+	appPaths, _ := configs.appPaths()
+
+	var downloadURLs, fingerprints, hashes []string
+	for appIndex, appPath := range appPaths {
+		p.logger.Printf("Uploading %v %d/%d", appPath, appIndex+1, len(appPaths))
+		artifact, err := p.uploadInternalAppSharingArtifact(ctx, service, configs.PackageName, appPath)
+		if err != nil {
+			return nil, err
+		}
+		p.logger.Printf(" download URL: %s", artifact.DownloadUrl)
+		p.logger.Debugf(" certificate fingerprint: %s, SHA-256: %s", artifact.CertificateFingerprint, artifact.Sha256)
+
+		downloadURLs = append(downloadURLs, artifact.DownloadUrl)
+		fingerprints = append(fingerprints, artifact.CertificateFingerprint)
+		hashes = append(hashes, artifact.Sha256)
+	}
+
+	return Outputs{
+		internalAppSharingDownloadURLKey:            strings.Join(downloadURLs, "|"),
+		internalAppSharingCertificateFingerprintKey: strings.Join(fingerprints, "|"),
+		internalAppSharingSHA256Key:                 strings.Join(hashes, "|"),
+	}, nil
+}
+
+// uploadInternalAppSharingArtifact uploads a single aab or apk file to internal app sharing.
+func (p *Publisher) uploadInternalAppSharingArtifact(ctx context.Context, service *androidpublisher.Service, packageName string, appPath string) (*androidpublisher.InternalAppSharingArtifact, error) {
+	appFile, err := os.Open(appPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open app (%s), error: %w", appPath, err)
+	}
+	defer func() {
+		if err := appFile.Close(); err != nil {
+			p.logger.Warnf("Failed to close app (%s), error: %s", appPath, err)
+		}
+	}()
+
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	internalAppSharingService := androidpublisher.NewInternalappsharingartifactsService(service)
+	if strings.ToLower(filepath.Ext(appPath)) == ".aab" {
+		uploadBundleCall := internalAppSharingService.Uploadbundle(packageName)
+		uploadBundleCall.Media(appFile, googleapi.ContentType("application/octet-stream"))
+		artifact, err := uploadBundleCall.Context(callCtx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload app bundle to internal app sharing, error: %w", err)
+		}
+		return artifact, nil
+	}
+
+	uploadApkCall := internalAppSharingService.Uploadapk(packageName)
+	uploadApkCall.Media(appFile, googleapi.ContentType("application/vnd.android.package-archive"))
+	artifact, err := uploadApkCall.Context(callCtx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload apk to internal app sharing, error: %w", err)
+	}
+	return artifact, nil
+}
@@ -0,0 +1,59 @@
+package googleplay
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublisher_uploadToInternalAppSharing(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	tests := []struct {
+		name  string
+		files []string
+	}{
+		{name: "apk", files: []string{"app.apk"}},
+		{name: "app bundle", files: []string{"app.aab"}},
+		{name: "multiple apks", files: []string{"app-arm64.apk", "app-x86.apk"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var appPaths, wantURLs, wantFingerprints, wantHashes []string
+			for i, file := range tt.files {
+				pth := filepath.Join(dir, file)
+				content := []byte("internal app sharing " + file)
+				require.NoError(t, os.WriteFile(pth, content, 0600))
+				appPaths = append(appPaths, pth)
+
+				hash := sha256.Sum256(content)
+				hexHash := hex.EncodeToString(hash[:])
+				wantURLs = append(wantURLs, "https://play.google.com/apps/test/"+packageName+"/"+strconv.Itoa(i+1))
+				wantFingerprints = append(wantFingerprints, strings.ToUpper(hexHash[:40]))
+				wantHashes = append(wantHashes, hexHash)
+			}
+
+			configs := Configs{PackageName: packageName, Mode: ModeInternalAppSharing, AppPath: strings.Join(appPaths, "|"), Logger: log.NewLogger()}
+			server, publisher, service := newEmulatedPublisher(t, configs)
+
+			outputs, err := publisher.uploadToInternalAppSharing(context.Background(), configs, service)
+			require.NoError(t, err)
+			assert.Equal(t, Outputs{
+				internalAppSharingDownloadURLKey:            strings.Join(wantURLs, "|"),
+				internalAppSharingCertificateFingerprintKey: strings.Join(wantFingerprints, "|"),
+				internalAppSharingSHA256Key:                 strings.Join(wantHashes, "|"),
+			}, outputs)
+			assert.Equal(t, 0, server.OpenEdits(packageName))
+			assert.Equal(t, 0, server.Commits(packageName))
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package googleplay
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+// The files of a store listing in the listings directory, in a directory per language like
+// <listings dir>/en-US/title.txt. The layout is the same as the metadata directory of fastlane supply.
+const (
+	listingTitleFile            = "title.txt"
+	listingShortDescriptionFile = "short_description.txt"
+	listingFullDescriptionFile  = "full_description.txt"
+	listingVideoFile            = "video.txt"
+)
+
+// listingFields returns the fields of the listing by their file name.
+func listingFields(listing *androidpublisher.Listing) map[string]*string {
+	return map[string]*string{
+		listingTitleFile:            &listing.Title,
+		listingShortDescriptionFile: &listing.ShortDescription,
+		listingFullDescriptionFile:  &listing.FullDescription,
+		listingVideoFile:            &listing.Video,
+	}
+}
+
+// PullListings writes the store listings of the app into the given directory, in a directory per language. Returns the
+// languages of the listings.
+func (p *Publisher) PullListings(ctx context.Context, service *androidpublisher.Service, configs Configs, dir string) ([]string, error) {
+	var listings []*androidpublisher.Listing
+	err := p.readInEdit(ctx, service, configs, func(ctx context.Context, editID string) error {
+		var err error
+		listings, err = p.listListings(ctx, service, configs.PackageName, editID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var languages []string
+	for _, listing := range listings {
+		languageDir := filepath.Join(dir, listing.Language)
+		if err := os.MkdirAll(languageDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create listing directory, error: %w", err)
+		}
+		for name, field := range listingFields(listing) {
+			if *field == "" && name == listingVideoFile {
+				continue
+			}
+			pth := filepath.Join(languageDir, name)
+			if err := os.WriteFile(pth, []byte(*field), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write listing to %s, error: %w", pth, err)
+			}
+		}
+		p.logger.Printf(" %s listing written to: %s", listing.Language, languageDir)
+		languages = append(languages, listing.Language)
+	}
+	sort.Strings(languages)
+	return languages, nil
+}
+
+// PushListings updates the store listings of the app from the directories per language of the given directory, then
+// commits the edit. Only the fields with a file are updated, the rest of the listing is kept. Returns the languages of
+// the updated listings.
+func (p *Publisher) PushListings(ctx context.Context, service *androidpublisher.Service, configs Configs, dir string) ([]string, error) {
+	local, err := readListings(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(local) == 0 {
+		return nil, fmt.Errorf("no listing found in %s", dir)
+	}
+
+	var languages []string
+	err = p.inEdit(ctx, service, configs, true, func(ctx context.Context, editID string) error {
+		languages, err = p.updateListings(ctx, service, configs.PackageName, editID, local)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return languages, nil
+}
+
+// updateListings updates the store listings of the app in the given edit with the fields read by readListings. Returns
+// the languages of the updated listings.
+func (p *Publisher) updateListings(ctx context.Context, service *androidpublisher.Service, packageName, editID string, local map[string]map[string]string) ([]string, error) {
+	listings, err := p.listListings(ctx, service, packageName, editID)
+	if err != nil {
+		return nil, err
+	}
+	current := map[string]*androidpublisher.Listing{}
+	for _, listing := range listings {
+		current[listing.Language] = listing
+	}
+
+	var languages []string
+	editsListingsService := androidpublisher.NewEditsListingsService(service)
+	for _, language := range sortedListingLanguages(local) {
+		listing := current[language]
+		if listing == nil {
+			listing = &androidpublisher.Listing{Language: language}
+		}
+		fields := listingFields(listing)
+		for name, value := range local[language] {
+			*fields[name] = value
+		}
+
+		callCtx, cancel := callContext(ctx)
+		_, err := editsListingsService.Update(packageName, editID, language, listing).Context(callCtx).Do()
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to update %s listing, error: %w", language, err)
+		}
+		p.logger.Printf(" updated %s listing", language)
+		languages = append(languages, language)
+	}
+	return languages, nil
+}
+
+func (p *Publisher) listListings(ctx context.Context, service *androidpublisher.Service, packageName, editID string) ([]*androidpublisher.Listing, error) {
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	response, err := androidpublisher.NewEditsListingsService(service).List(packageName, editID).Context(callCtx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list listings, error: %w", err)
+	}
+	return response.Listings, nil
+}
+
+// readListings reads the listing files of the directories per language of the given directory, by language and file
+// name. Trailing newlines are trimmed.
+func readListings(dir string) (map[string]map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read listings directory, error: %w", err)
+	}
+
+	listings := map[string]map[string]string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		fields := map[string]string{}
+		for name := range listingFields(&androidpublisher.Listing{}) {
+			content, err := os.ReadFile(filepath.Join(dir, entry.Name(), name))
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to read listing, error: %w", err)
+			}
+			fields[name] = strings.TrimRight(string(content), "\r\n")
+		}
+		if len(fields) > 0 {
+			listings[entry.Name()] = fields
+		}
+	}
+	return listings, nil
+}
+
+func sortedListingLanguages(listings map[string]map[string]string) []string {
+	var languages []string
+	for language := range listings {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
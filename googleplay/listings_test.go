@@ -0,0 +1,54 @@
+package googleplay
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-google-play-deploy/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+func TestPublisher_pullAndPushListings(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	configs := Configs{PackageName: packageName}
+	server, publisher, service := newEmulatedPublisher(t, configs,
+		emulator.WithListing(androidpublisher.Listing{Language: "en-US", Title: "Sample", ShortDescription: "A sample", FullDescription: "A sample app"}),
+		emulator.WithListing(androidpublisher.Listing{Language: "de-DE", Title: "Beispiel", ShortDescription: "Ein Beispiel", FullDescription: "Eine Beispiel-App"}),
+	)
+
+	dir := t.TempDir()
+	languages, err := publisher.PullListings(context.Background(), service, configs, dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"de-DE", "en-US"}, languages)
+	content, err := os.ReadFile(filepath.Join(dir, "en-US", listingTitleFile))
+	require.NoError(t, err)
+	assert.Equal(t, "Sample", string(content))
+	assert.NoFileExists(t, filepath.Join(dir, "en-US", listingVideoFile))
+	assert.Equal(t, 0, server.Commits(packageName))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "en-US", listingShortDescriptionFile), []byte("An updated sample\n"), 0644))
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "de-DE")))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "fr-FR"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fr-FR", listingTitleFile), []byte("Exemple"), 0644))
+
+	languages, err = publisher.PushListings(context.Background(), service, configs, dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"en-US", "fr-FR"}, languages)
+	assert.Equal(t, 1, server.Commits(packageName))
+	assert.Equal(t, 0, server.OpenEdits(packageName))
+
+	assert.Equal(t, &androidpublisher.Listing{Language: "en-US", Title: "Sample", ShortDescription: "An updated sample", FullDescription: "A sample app"}, server.Listing(packageName, "en-US"))
+	assert.Equal(t, &androidpublisher.Listing{Language: "fr-FR", Title: "Exemple"}, server.Listing(packageName, "fr-FR"))
+	assert.Equal(t, "Beispiel", server.Listing(packageName, "de-DE").Title)
+}
+
+func TestPublisher_PushListings_emptyDirectory(t *testing.T) {
+	publisher := New(Options{})
+	_, err := publisher.PushListings(context.Background(), nil, Configs{PackageName: "io.bitrise.sample"}, t.TempDir())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no listing found")
+}
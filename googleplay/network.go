@@ -0,0 +1,275 @@
+package googleplay
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/hashicorp/go-retryablehttp"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+// createHTTPClient creates an HTTP client for the communication during the uploads.
+func (p *Publisher) createHTTPClient(configs Configs) (*http.Client, error) {
+	transport, err := newTransport(configs)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonContent, err := p.readJSONKey(configs, transport)
+	if err != nil {
+		return nil, err
+	}
+
+	// Token requests and API calls are both retried, the latter through the oauth2 transport built on this client.
+	retryClient := &http.Client{Transport: p.newRetryTransport(transport, defaultRetrySettings, configs.apiBasePaths())}
+	refreshCtx := context.WithValue(context.Background(), oauth2.HTTPClient, retryClient)
+
+	if configs.ImpersonateServiceAccount == "" {
+		credentials, err := credentialsFromJSON(refreshCtx, jsonContent, androidpublisher.AndroidpublisherScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create credentials from json key file, error: %s", err)
+		}
+
+		return oauth2.NewClient(refreshCtx, credentials.TokenSource), nil
+	}
+
+	// The source credentials are only used to generate tokens of the impersonated service account.
+	p.logger.Printf(" impersonating service account: %s", configs.ImpersonateServiceAccount)
+	sourceCredentials, err := credentialsFromJSON(refreshCtx, jsonContent, cloudPlatformScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credentials from json key file, error: %s", err)
+	}
+	tokenSource := oauth2.ReuseTokenSource(nil, impersonatedTokenSource{
+		ctx:                  refreshCtx,
+		source:               sourceCredentials.TokenSource,
+		targetServiceAccount: configs.ImpersonateServiceAccount,
+		scopes:               []string{androidpublisher.AndroidpublisherScope},
+	})
+
+	return oauth2.NewClient(refreshCtx, tokenSource), nil
+}
+
+// readJSONKey returns the content of the json key. The input is either the key itself (raw or base64-encoded JSON),
+// a local file path or a remote URL to download the key from. Inline keys are parsed in memory only.
+func (p *Publisher) readJSONKey(configs Configs, transport http.RoundTripper) ([]byte, error) {
+	keyInput := string(configs.JSONKeyPath)
+	if jsonContent, ok := inlineJSONKey(keyInput); ok {
+		p.logger.Printf(" using inline json key")
+		return jsonContent, nil
+	}
+
+	jsonKeyPth, isRemote, err := parseURI(keyInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare key path, error: %s", err)
+	}
+
+	var jsonContent []byte
+	if isRemote {
+		downloadURL, err := httpDownloadURL(jsonKeyPth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare key download url, error: %s", err)
+		}
+		headers, err := configs.jsonKeyDownloadHeaders()
+		if err != nil {
+			return nil, err
+		}
+
+		jsonContent, err = p.downloadContentWithRetry(downloadURL, headers, maxJSONKeySize, transport, 3, 3*time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download json key file, error: %s", err)
+		}
+	} else {
+		jsonContent, err = fileutil.ReadBytesFromFile(jsonKeyPth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read json key file %v, error: %s", jsonKeyPth, err)
+		}
+	}
+
+	if configs.JSONKeySHA256 != "" {
+		if err := verifySHA256(jsonContent, configs.JSONKeySHA256); err != nil {
+			return nil, fmt.Errorf("failed to verify json key, error: %s", err)
+		}
+		p.logger.Printf(" json key checksum verified")
+	}
+	return jsonContent, nil
+}
+
+// inlineJSONKey returns the key if the input is the content of a json key (raw or base64-encoded JSON object) instead
+// of a path or URL.
+func inlineJSONKey(keyInput string) ([]byte, bool) {
+	keyInput = strings.TrimSpace(keyInput)
+	if strings.HasPrefix(keyInput, "{") {
+		return []byte(keyInput), true
+	}
+
+	compact := strings.Join(strings.Fields(keyInput), "")
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		decoded, err := encoding.DecodeString(compact)
+		if err == nil && strings.HasPrefix(strings.TrimSpace(string(decoded)), "{") {
+			return decoded, true
+		}
+	}
+	return nil, false
+}
+
+// Credential types accepted in the json key file.
+const (
+	credentialsTypeServiceAccount             = "service_account"
+	credentialsTypeExternalAccount            = "external_account"
+	credentialsTypeAuthorizedUser             = "authorized_user"
+	credentialsTypeImpersonatedServiceAccount = "impersonated_service_account"
+)
+
+// credentialsFromJSON creates credentials from a Google credential JSON. Besides service account keys it accepts
+// external accounts (workload identity federation), authorized users and impersonated service accounts.
+func credentialsFromJSON(ctx context.Context, jsonContent []byte, scopes ...string) (*google.Credentials, error) {
+	credType, err := credentialsType(jsonContent)
+	if err != nil {
+		return nil, err
+	}
+
+	switch credType {
+	case credentialsTypeServiceAccount, credentialsTypeExternalAccount, credentialsTypeAuthorizedUser, credentialsTypeImpersonatedServiceAccount:
+	case "":
+		return nil, errors.New("missing 'type' field in credentials")
+	default:
+		return nil, fmt.Errorf("unsupported credential type: %s", credType)
+	}
+
+	return google.CredentialsFromJSON(ctx, jsonContent, scopes...)
+}
+
+// credentialsType returns the value of the 'type' field of a Google credential JSON.
+func credentialsType(jsonContent []byte) (string, error) {
+	var credentials struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(jsonContent, &credentials); err != nil {
+		return "", fmt.Errorf("invalid credentials json, error: %s", err)
+	}
+	return credentials.Type, nil
+}
+
+// parseURI parses the given URI to return the path from it if it is a local file, if it is remote the bool value is
+// true.
+func parseURI(keyURI string) (string, bool, error) {
+	jsonURL, err := url.Parse(keyURI)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to parse url (%s), error: %s", keyURI, err)
+	}
+
+	switch jsonURL.Scheme {
+	case "http", "https", "gs", "s3":
+		return keyURI, true, nil
+	}
+	return strings.TrimPrefix(keyURI, "file://"), false, nil
+}
+
+// httpDownloadURL converts gs:// and s3:// URLs to the equivalent https:// URL of the storage service. The query is
+// kept, so presigned (signed) URLs can be given in the shorter form, for example:
+// gs://bucket/key.json?X-Goog-Signature=... -> https://storage.googleapis.com/bucket/key.json?X-Goog-Signature=...
+// s3://bucket/key.json?X-Amz-Signature=... -> https://bucket.s3.amazonaws.com/key.json?X-Amz-Signature=...
+func httpDownloadURL(keyURI string) (string, error) {
+	keyURL, err := url.Parse(keyURI)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse url, error: %s", err)
+	}
+
+	switch keyURL.Scheme {
+	case "gs":
+		keyURL.Path = "/" + keyURL.Host + keyURL.Path
+		keyURL.Host = "storage.googleapis.com"
+	case "s3":
+		keyURL.Host = keyURL.Host + ".s3.amazonaws.com"
+	default:
+		return keyURI, nil
+	}
+	keyURL.Scheme = "https"
+	return keyURL.String(), nil
+}
+
+// redactURL returns the given URL without credentials and query, so that it can be logged.
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "<invalid url>"
+	}
+	parsed.User = nil
+	parsed.RawQuery = ""
+	parsed.Fragment = ""
+	return parsed.String()
+}
+
+// maxJSONKeySize is the maximum accepted size of a downloaded json key.
+const maxJSONKeySize = 1 << 20
+
+// downloadContentWithRetry downloads content from the given URL using a retryable HTTP client, sending the given
+// headers. Fails if the response is not successful or the content is larger than maxSize bytes.
+func (p *Publisher) downloadContentWithRetry(downloadURL string, headers http.Header, maxSize int64, transport http.RoundTripper, numberOfRetries int, waitInterval time.Duration) ([]byte, error) {
+	retryClient := retryablehttp.NewClient()
+	retryClient.HTTPClient.Transport = transport
+	retryClient.Logger = nil
+	retryClient.RetryWaitMin = waitInterval
+	retryClient.RetryMax = numberOfRetries
+	retryClient.CheckRetry = retryablehttp.DefaultRetryPolicy
+
+	req, err := retryablehttp.NewRequest(http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return []byte{}, fmt.Errorf("failed to create request for (%s), error: %s", redactURL(downloadURL), err)
+	}
+	for name, values := range headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	resp, err := retryClient.Do(req)
+	if err != nil {
+		return []byte{}, fmt.Errorf("failed to download from (%s), error: %s", redactURL(downloadURL), err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			p.logger.Warnf("failed to close (%s) body", redactURL(downloadURL))
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return []byte{}, fmt.Errorf("failed to download from (%s), status: %d", redactURL(downloadURL), resp.StatusCode)
+	}
+	if resp.ContentLength > maxSize {
+		return []byte{}, fmt.Errorf("content size (%d bytes) exceeds the limit of %d bytes", resp.ContentLength, maxSize)
+	}
+
+	contentBytes, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return []byte{}, fmt.Errorf("failed to read received content, error: %s", err)
+	}
+	if int64(len(contentBytes)) > maxSize {
+		return []byte{}, fmt.Errorf("content size exceeds the limit of %d bytes", maxSize)
+	}
+
+	return contentBytes, nil
+}
+
+// verifySHA256 checks if the SHA-256 checksum of the content matches the expected hex encoded checksum.
+func verifySHA256(content []byte, expected string) error {
+	sum := sha256.Sum256(content)
+	actual := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(actual, strings.TrimSpace(expected)) {
+		return fmt.Errorf("checksum mismatch, expected SHA-256: %s, actual: %s", expected, actual)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package googleplay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-steputils/stepconf"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSTSServer starts a local stand-in of the Security Token Service, exchanging the given subject token to the given
+// access token.
+func newSTSServer(t *testing.T, subjectToken, accessToken string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("subject_token") != subjectToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token": "%s", "issued_token_type": "urn:ietf:params:oauth:token-type:access_token", "token_type": "Bearer", "expires_in": 3600}`, accessToken)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// writeExternalAccountKey writes an external account credential JSON, reading the given OIDC token from a local file
+// and exchanging it at the given token URL.
+func writeExternalAccountKey(t *testing.T, tokenURL, oidcToken string) string {
+	dir := t.TempDir()
+	tokenPth := filepath.Join(dir, "oidc_token")
+	require.NoError(t, os.WriteFile(tokenPth, []byte(oidcToken), 0600))
+
+	keyPth := filepath.Join(dir, "credentials.json")
+	key := fmt.Sprintf(`{
+  "type": "external_account",
+  "audience": "//iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/ci/providers/ci",
+  "subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+  "token_url": "%s",
+  "credential_source": {"file": "%s"}
+}`, tokenURL, tokenPth)
+	require.NoError(t, os.WriteFile(keyPth, []byte(key), 0600))
+	return keyPth
+}
+
+func Test_createHTTPClient_externalAccount(t *testing.T) {
+	sts := newSTSServer(t, "oidc-token", "federated-access-token")
+
+	var authorization string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer api.Close()
+
+	keyPth := writeExternalAccountKey(t, sts.URL, "oidc-token")
+
+	client, err := NewPublisher(log.NewLogger()).createHTTPClient(Configs{JSONKeyPath: stepconf.Secret("file://" + keyPth)})
+	require.NoError(t, err)
+
+	resp, err := client.Get(api.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "Bearer federated-access-token", authorization)
+}
+
+func Test_credentialsType(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"service account", `{"type": "service_account"}`, credentialsTypeServiceAccount, false},
+		{"external account", `{"type": "external_account"}`, credentialsTypeExternalAccount, false},
+		{"missing type", `{}`, "", false},
+		{"invalid json", `not json`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := credentialsType([]byte(tt.content))
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_inlineJSONKey(t *testing.T) {
+	key := `{"type": "service_account"}`
+	tests := []struct {
+		name     string
+		input    string
+		want     string
+		isInline bool
+	}{
+		{"raw json", key, key, true},
+		{"raw json with whitespace", "\n  " + key + "\n", key, true},
+		{"base64 json", base64.StdEncoding.EncodeToString([]byte(key)), key, true},
+		{"wrapped base64 json", "eyJ0eXBlIjogInNlcnZp\nY2VfYWNjb3VudCJ9", key, true},
+		{"file path", "file:///bitrise/key.json", "", false},
+		{"url", "https://example.com/key.json", "", false},
+		{"plain path", "/bitrise/key.json", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := inlineJSONKey(tt.input)
+			assert.Equal(t, tt.isInline, ok)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func Test_readJSONKey_remote(t *testing.T) {
+	key := `{"type": "service_account"}`
+	keyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer vault-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/large.json" {
+			_, _ = w.Write(bytes.Repeat([]byte(" "), maxJSONKeySize+1))
+			return
+		}
+		_, _ = w.Write([]byte(key))
+	}))
+	defer keyServer.Close()
+
+	sum := sha256.Sum256([]byte(key))
+	checksum := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name    string
+		configs Configs
+		wantErr bool
+	}{
+		{
+			name:    "authenticated download",
+			configs: Configs{JSONKeyPath: stepconf.Secret(keyServer.URL + "/key.json"), JSONKeyDownloadHeaders: "Authorization: Bearer vault-token"},
+		},
+		{
+			name:    "missing auth header",
+			configs: Configs{JSONKeyPath: stepconf.Secret(keyServer.URL + "/key.json")},
+			wantErr: true,
+		},
+		{
+			name: "matching checksum",
+			configs: Configs{
+				JSONKeyPath:            stepconf.Secret(keyServer.URL + "/key.json"),
+				JSONKeyDownloadHeaders: "Authorization: Bearer vault-token",
+				JSONKeySHA256:          strings.ToUpper(checksum),
+			},
+		},
+		{
+			name: "checksum mismatch",
+			configs: Configs{
+				JSONKeyPath:            stepconf.Secret(keyServer.URL + "/key.json"),
+				JSONKeyDownloadHeaders: "Authorization: Bearer vault-token",
+				JSONKeySHA256:          strings.Repeat("0", 64),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "size limit exceeded",
+			configs: Configs{JSONKeyPath: stepconf.Secret(keyServer.URL + "/large.json"), JSONKeyDownloadHeaders: "Authorization: Bearer vault-token"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.configs.Logger = log.NewLogger()
+			got, err := NewPublisher(log.NewLogger()).readJSONKey(tt.configs, http.DefaultTransport)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, key, string(got))
+		})
+	}
+}
+
+func Test_httpDownloadURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"https", "https://example.com/key.json", "https://example.com/key.json"},
+		{"gs", "gs://bucket/ci/key.json?X-Goog-Signature=abc", "https://storage.googleapis.com/bucket/ci/key.json?X-Goog-Signature=abc"},
+		{"s3", "s3://bucket/ci/key.json?X-Amz-Signature=abc", "https://bucket.s3.amazonaws.com/ci/key.json?X-Amz-Signature=abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := httpDownloadURL(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfigs_jsonKeyDownloadHeaders(t *testing.T) {
+	headers, err := Configs{JSONKeyDownloadHeaders: "Authorization: Bearer a:b\nX-Vault-Namespace: ci\n"}.jsonKeyDownloadHeaders()
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer a:b", headers.Get("Authorization"))
+	assert.Equal(t, "ci", headers.Get("X-Vault-Namespace"))
+
+	_, err = Configs{JSONKeyDownloadHeaders: "Bearer token"}.jsonKeyDownloadHeaders()
+	assert.Error(t, err)
+}
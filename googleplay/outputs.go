@@ -0,0 +1,107 @@
+package googleplay
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+const (
+	editIDKey         = "GOOGLE_PLAY_EDIT_ID"
+	versionCodesKey   = "GOOGLE_PLAY_VERSION_CODES"
+	releaseNameKey    = "GOOGLE_PLAY_RELEASE_NAME"
+	trackKey          = "GOOGLE_PLAY_TRACK"
+	releaseStatusKey  = "GOOGLE_PLAY_RELEASE_STATUS"
+	userFractionKey   = "GOOGLE_PLAY_USER_FRACTION"
+	artifactSHA256Key = "GOOGLE_PLAY_ARTIFACT_SHA256"
+	playConsoleURLKey = "GOOGLE_PLAY_CONSOLE_URL"
+	// playConsoleURL is the home of the Google Play Console. The links opening an app need the IDs of the developer
+	// account and of the app in the console, which the API doesn't return.
+	playConsoleURL = "https://play.google.com/console/"
+)
+
+// Outputs are the outputs of a deployment by the environment variable the step exports them to, like
+// GOOGLE_PLAY_EDIT_ID.
+type Outputs map[string]string
+
+// add sets the given outputs.
+func (o Outputs) add(outputs Outputs) {
+	for key, value := range outputs {
+		o[key] = value
+	}
+}
+
+// Keys returns the keys of the outputs in alphabetical order.
+func (o Outputs) Keys() []string {
+	var keys []string
+	for key := range o {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// deployment is the result of a committed edit.
+type deployment struct {
+	EditID       string
+	Track        string
+	VersionCodes []int64
+	// ArtifactSHA256s are the SHA-256 hashes of the uploaded apps, in the order of the app paths.
+	ArtifactSHA256s []string
+	// Release is the release of the track with the uploaded version codes, as returned by Google Play.
+	Release *androidpublisher.TrackRelease
+}
+
+// outputs returns the output environment variables describing the deployment.
+func (d deployment) outputs() Outputs {
+	versionCodes := append([]int64(nil), d.VersionCodes...)
+	sort.Slice(versionCodes, func(i, j int) bool { return versionCodes[i] < versionCodes[j] })
+	var versionCodeStrings []string
+	for _, versionCode := range versionCodes {
+		versionCodeStrings = append(versionCodeStrings, strconv.FormatInt(versionCode, 10))
+	}
+
+	outputs := Outputs{
+		editIDKey:         d.EditID,
+		versionCodesKey:   strings.Join(versionCodeStrings, "|"),
+		trackKey:          d.Track,
+		artifactSHA256Key: strings.Join(d.ArtifactSHA256s, "|"),
+		playConsoleURLKey: playConsoleURL,
+	}
+	if d.Release != nil {
+		outputs[releaseNameKey] = d.Release.Name
+		outputs[releaseStatusKey] = d.Release.Status
+		if shouldApplyUserFraction(d.Release.Status) {
+			outputs[userFractionKey] = strconv.FormatFloat(d.Release.UserFraction, 'f', -1, 64)
+		}
+	}
+	return outputs
+}
+
+// artifactHashes returns the SHA-256 hashes of the given apps.
+func artifactHashes(artifacts []UploadedArtifact) []string {
+	var hashes []string
+	for _, artifact := range artifacts {
+		hashes = append(hashes, artifact.SHA256)
+	}
+	return hashes
+}
+
+// releaseWithVersionCodes returns the release of the track containing any of the given version codes.
+func releaseWithVersionCodes(track *androidpublisher.Track, versionCodes []int64) *androidpublisher.TrackRelease {
+	if track == nil {
+		return nil
+	}
+	for _, release := range track.Releases {
+		for _, releaseVersionCode := range release.VersionCodes {
+			for _, versionCode := range versionCodes {
+				if releaseVersionCode == versionCode {
+					return release
+				}
+			}
+		}
+	}
+	return nil
+}
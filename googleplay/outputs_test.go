@@ -0,0 +1,72 @@
+package googleplay
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+func Test_deployment_outputs(t *testing.T) {
+	tests := []struct {
+		name       string
+		deployment deployment
+		want       Outputs
+	}{
+		{
+			name: "staged rollout",
+			deployment: deployment{
+				EditID:          "edit-1",
+				Track:           "production",
+				VersionCodes:    []int64{12, 11},
+				ArtifactSHA256s: []string{"aaa", "bbb"},
+				Release:         &androidpublisher.TrackRelease{Name: "1.2.0", Status: releaseStatusInProgress, UserFraction: 0.25, VersionCodes: []int64{11, 12}},
+			},
+			want: Outputs{
+				editIDKey:         "edit-1",
+				versionCodesKey:   "11|12",
+				releaseNameKey:    "1.2.0",
+				trackKey:          "production",
+				releaseStatusKey:  releaseStatusInProgress,
+				userFractionKey:   "0.25",
+				artifactSHA256Key: "aaa|bbb",
+				playConsoleURLKey: "https://play.google.com/console/",
+			},
+		},
+		{
+			name: "completed release has no user fraction",
+			deployment: deployment{
+				EditID:          "edit-2",
+				Track:           "beta",
+				VersionCodes:    []int64{3},
+				ArtifactSHA256s: []string{"ccc"},
+				Release:         &androidpublisher.TrackRelease{Name: "3", Status: releaseStatusCompleted, VersionCodes: []int64{3}},
+			},
+			want: Outputs{
+				editIDKey:         "edit-2",
+				versionCodesKey:   "3",
+				releaseNameKey:    "3",
+				trackKey:          "beta",
+				releaseStatusKey:  releaseStatusCompleted,
+				artifactSHA256Key: "ccc",
+				playConsoleURLKey: "https://play.google.com/console/",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.deployment.outputs())
+		})
+	}
+}
+
+func Test_releaseWithVersionCodes(t *testing.T) {
+	completed := &androidpublisher.TrackRelease{Status: releaseStatusCompleted, VersionCodes: []int64{1}}
+	inProgress := &androidpublisher.TrackRelease{Status: releaseStatusInProgress, VersionCodes: []int64{2, 3}}
+	track := &androidpublisher.Track{Releases: []*androidpublisher.TrackRelease{completed, inProgress}}
+
+	assert.Equal(t, inProgress, releaseWithVersionCodes(track, []int64{3}))
+	assert.Equal(t, completed, releaseWithVersionCodes(track, []int64{1}))
+	assert.Nil(t, releaseWithVersionCodes(track, []int64{4}))
+	assert.Nil(t, releaseWithVersionCodes(nil, []int64{1}))
+}
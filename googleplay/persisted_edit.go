@@ -0,0 +1,225 @@
+package googleplay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+const (
+	editStateFileName = "google-play-edit.json"
+	editStatePathKey  = "GOOGLE_PLAY_EDIT_STATE_PATH"
+)
+
+// persistedEdit is the state of an uncommitted edit, written by the upload_only mode and committed by the commit_edit
+// mode.
+type persistedEdit struct {
+	PackageName     string    `json:"package_name"`
+	EditID          string    `json:"edit_id"`
+	Track           string    `json:"track"`
+	VersionCodes    []int64   `json:"version_codes"`
+	ArtifactSHA256s []string  `json:"artifact_sha256s"`
+	CreatedAt       time.Time `json:"created_at"`
+	// ExpiresAt is when Google Play deletes the edit if it is not committed.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+func newPersistedEdit(configs Configs, appEdit *androidpublisher.AppEdit, versionCodes []int64, hashes []string) persistedEdit {
+	return persistedEdit{
+		PackageName:     configs.PackageName,
+		EditID:          appEdit.Id,
+		Track:           configs.Track,
+		VersionCodes:    versionCodes,
+		ArtifactSHA256s: hashes,
+		CreatedAt:       time.Now().UTC(),
+		ExpiresAt:       editExpiry(appEdit),
+	}
+}
+
+// persistEdit writes the edit state into the deploy directory. Returns its path and the edit ID as outputs.
+func (p *Publisher) persistEdit(deployDir string, edit persistedEdit) (Outputs, error) {
+	content, err := json.MarshalIndent(edit, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize edit state, error: %w", err)
+	}
+
+	pth := filepath.Join(deployDir, editStateFileName)
+	if err := os.WriteFile(pth, content, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write edit state to %s, error: %w", pth, err)
+	}
+	p.logger.Printf(" edit %s persisted to: %s", edit.EditID, pth)
+	if !edit.ExpiresAt.IsZero() {
+		p.logger.Printf(" the edit expires at: %s", edit.ExpiresAt.Format(time.RFC3339))
+	}
+
+	return Outputs{
+		editStatePathKey: pth,
+		editIDKey:        edit.EditID,
+	}, nil
+}
+
+// readPersistedEdit reads the edit state written by persistEdit.
+func readPersistedEdit(pth string) (persistedEdit, error) {
+	content, err := os.ReadFile(pth)
+	if err != nil {
+		return persistedEdit{}, fmt.Errorf("failed to read edit state, error: %w", err)
+	}
+
+	var edit persistedEdit
+	if err := json.Unmarshal(content, &edit); err != nil {
+		return persistedEdit{}, fmt.Errorf("failed to parse edit state, error: %w", err)
+	}
+	if edit.PackageName == "" || edit.EditID == "" || edit.Track == "" {
+		return persistedEdit{}, errors.New("invalid edit state: package name, edit ID and track are required")
+	}
+	return edit, nil
+}
+
+// commitPersistedEdit reopens the edit persisted by the upload_only mode, updates the release notes of its release if
+// a whatsnews directory is given, then validates and commits it. In dry run mode the edit is only validated. The
+// outputs of the deployment are added to the given ones.
+func (p *Publisher) commitPersistedEdit(ctx context.Context, service *androidpublisher.Service, configs Configs, outputs Outputs, changesNotSentForReview bool, dryRun bool) (err error) {
+	edit, err := readPersistedEdit(configs.EditStatePath)
+	if err != nil {
+		return err
+	}
+	if configs.PackageName != "" && configs.PackageName != edit.PackageName {
+		return fmt.Errorf("the persisted edit belongs to %s, not to %s", edit.PackageName, configs.PackageName)
+	}
+	configs.PackageName = edit.PackageName
+	configs.Track = edit.Track
+
+	report := newDeploymentReport(configs, dryRun)
+	report.startAttempt()
+	report.setEditID(edit.EditID)
+	defer func() {
+		if err != nil {
+			err = interruptionError(ctx, report.phase(), err)
+		}
+		report.endAttempt(err)
+		report.finish(err)
+		reportOutputs, reportErr := p.writeDeploymentReport(configs, report)
+		if reportErr != nil {
+			p.logger.Warnf("Unable to write deployment report, error: %s", reportErr)
+		}
+		outputs.add(reportOutputs)
+	}()
+
+	//
+	// Open edit
+	fmt.Println()
+	report.startPhase("Open edit")
+	p.logger.Infof("Open persisted edit")
+	p.logger.Printf(" editID: %s", edit.EditID)
+	p.logger.Printf(" track: %s, version codes: %v", edit.Track, edit.VersionCodes)
+	getCtx, cancelGet := callContext(ctx)
+	defer cancelGet()
+	if _, err := androidpublisher.NewEditsService(service).Get(edit.PackageName, edit.EditID).Context(getCtx).Do(); err != nil {
+		return fmt.Errorf("Failed to open edit %s, error: %w", edit.EditID, err)
+	}
+	p.logger.Donef("Edit opened")
+
+	//
+	// Fetch the release of the edit, and update its release notes if requested
+	fmt.Println()
+	report.startPhase("Update release notes")
+	p.logger.Infof("Release of the %s track", edit.Track)
+	release, err := p.updatePersistedRelease(ctx, configs, service, edit)
+	if err != nil {
+		return fmt.Errorf("Failed to update release of the edit, error: %w", err)
+	}
+	p.logger.Donef("Release up to date")
+
+	//
+	// Update the store listings if requested
+	if configs.ListingsDir != "" {
+		fmt.Println()
+		report.startPhase("Update listings")
+		p.logger.Infof("Updating listings from %s", configs.ListingsDir)
+		local, err := readListings(configs.ListingsDir)
+		if err != nil {
+			return fmt.Errorf("Failed to read listings, error: %w", err)
+		}
+		if _, err := p.updateListings(ctx, service, edit.PackageName, edit.EditID, local); err != nil {
+			return fmt.Errorf("Failed to update listings, error: %w", err)
+		}
+		p.logger.Donef("Listings updated")
+	}
+
+	//
+	// Validate edit
+	fmt.Println()
+	report.startPhase("Validate edit")
+	p.logger.Infof("Validating edit")
+	if err := p.validateEdit(ctx, service, edit.PackageName, edit.EditID); err != nil {
+		return fmt.Errorf("Failed to validate edit, error: %w", err)
+	}
+	p.logger.Donef("Edit validated")
+	if dryRun {
+		p.logger.Warnf("Dry run: the edit is not committed")
+		return nil
+	}
+
+	//
+	// Commit edit
+	fmt.Println()
+	report.startPhase("Commit edit")
+	p.logger.Infof("Committing edit")
+	if err := p.commitEdit(ctx, service, edit.PackageName, edit.EditID, changesNotSentForReview); err != nil {
+		return fmt.Errorf("Failed to commit edit, error: %w", err)
+	}
+	p.logger.Donef("Edit committed")
+
+	result := deployment{
+		EditID:          edit.EditID,
+		Track:           edit.Track,
+		VersionCodes:    edit.VersionCodes,
+		ArtifactSHA256s: edit.ArtifactSHA256s,
+		Release:         release,
+	}
+	outputs.add(result.outputs())
+	return nil
+}
+
+// updatePersistedRelease returns the release of the persisted edit, with its release notes updated from the whatsnews
+// directory if given.
+func (p *Publisher) updatePersistedRelease(ctx context.Context, configs Configs, service *androidpublisher.Service, edit persistedEdit) (*androidpublisher.TrackRelease, error) {
+	editsTracksService := androidpublisher.NewEditsTracksService(service)
+
+	getCtx, cancelGet := callContext(ctx)
+	defer cancelGet()
+	track, err := editsTracksService.Get(edit.PackageName, edit.EditID, edit.Track).Context(getCtx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get track %s, error: %w", edit.Track, err)
+	}
+	release := releaseWithVersionCodes(track, edit.VersionCodes)
+	if release == nil {
+		return nil, fmt.Errorf("no release of the %s track has the version codes %v", edit.Track, edit.VersionCodes)
+	}
+	p.logger.Printf(" release: %s, status: %s", release.Name, release.Status)
+
+	if configs.WhatsnewsDir == "" {
+		return release, nil
+	}
+	if err := p.updateListing(configs.WhatsnewsDir, release); err != nil {
+		return nil, fmt.Errorf("failed to update listing, reason: %w", err)
+	}
+
+	updateCtx, cancelUpdate := callContext(ctx)
+	defer cancelUpdate()
+	track, err = editsTracksService.Update(edit.PackageName, edit.EditID, edit.Track, track).Context(updateCtx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("update call failed, error: %w", err)
+	}
+	p.logger.Printf(" updated release notes of track: %s", track.Track)
+	if updated := releaseWithVersionCodes(track, edit.VersionCodes); updated != nil {
+		return updated, nil
+	}
+	return release, nil
+}
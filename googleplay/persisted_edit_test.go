@@ -0,0 +1,123 @@
+package googleplay
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-google-play-deploy/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+func TestPublisher_uploadOnlyThenCommitEdit(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+
+	tests := []struct {
+		name string
+		// beforeCommit runs between the upload and the commit of the persisted edit.
+		beforeCommit func(t *testing.T, server *emulator.Server, publisher *Publisher, service *androidpublisher.Service)
+		packageName  string
+		wantErrCode  FailureCode
+	}{
+		{
+			name: "committed with release notes",
+		},
+		{
+			name: "edit deleted by an other commit",
+			beforeCommit: func(t *testing.T, server *emulator.Server, publisher *Publisher, service *androidpublisher.Service) {
+				edit, err := androidpublisher.NewEditsService(service).Insert(packageName, &androidpublisher.AppEdit{}).Do()
+				require.NoError(t, err)
+				require.NoError(t, publisher.commitEdit(context.Background(), service, packageName, edit.Id, false))
+			},
+			wantErrCode: FailureEditDeleted,
+		},
+		{
+			name:        "edit of an other app",
+			packageName: "io.bitrise.other",
+			wantErrCode: FailureUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployDir := t.TempDir()
+
+			configs := Configs{
+				Mode:        ModeUploadOnly,
+				PackageName: packageName,
+				AppPath:     writeBundles(t, "1"),
+				Track:       "beta",
+				DeployDir:   deployDir,
+				Logger:      log.NewLogger(),
+			}
+			server, publisher, service := newEmulatedPublisher(t, configs)
+
+			require.NoError(t, publisher.executeEdit(context.Background(), service, configs, Outputs{}, false, false))
+			assert.Equal(t, 0, server.Commits(packageName))
+			assert.Equal(t, 1, server.OpenEdits(packageName))
+
+			statePath := filepath.Join(deployDir, editStateFileName)
+			edit, err := readPersistedEdit(statePath)
+			require.NoError(t, err)
+			assert.Equal(t, "beta", edit.Track)
+			assert.Equal(t, []int64{1}, edit.VersionCodes)
+			assert.False(t, edit.ExpiresAt.IsZero())
+
+			if tt.beforeCommit != nil {
+				tt.beforeCommit(t, server, publisher, service)
+			}
+
+			whatsnewsDir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(whatsnewsDir, "whatsnew-en-US"), []byte("Bug fixes"), 0600))
+			listingsDir := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(listingsDir, "en-US"), 0700))
+			require.NoError(t, os.WriteFile(filepath.Join(listingsDir, "en-US", "title.txt"), []byte("Sample"), 0600))
+			commitConfigs := Configs{
+				Mode:          ModeCommitEdit,
+				PackageName:   packageName,
+				Track:         "production",
+				EditStatePath: statePath,
+				WhatsnewsDir:  whatsnewsDir,
+				ListingsDir:   listingsDir,
+				Logger:        log.NewLogger(),
+			}
+			if tt.packageName != "" {
+				commitConfigs.PackageName = tt.packageName
+			}
+
+			err = publisher.commitPersistedEdit(context.Background(), service, commitConfigs, Outputs{}, false, false)
+			if tt.wantErrCode != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantErrCode, ClassifyError(err).Code)
+				return
+			}
+			require.NoError(t, err)
+
+			track := server.Track(packageName, "beta")
+			require.NotNil(t, track)
+			assert.Equal(t, []*androidpublisher.TrackRelease{{
+				Status:       releaseStatusCompleted,
+				VersionCodes: []int64{1},
+				ReleaseNotes: []*androidpublisher.LocalizedText{{Language: "en-US", Text: "Bug fixes"}},
+			}}, track.Releases)
+			listing := server.Listing(packageName, "en-US")
+			require.NotNil(t, listing)
+			assert.Equal(t, "Sample", listing.Title)
+			assert.Equal(t, 0, server.OpenEdits(packageName))
+		})
+	}
+}
+
+func Test_readPersistedEdit(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), editStateFileName)
+	require.NoError(t, os.WriteFile(pth, []byte(`{"package_name": "io.bitrise.sample", "edit_id": "1"}`), 0600))
+
+	_, err := readPersistedEdit(pth)
+	assert.EqualError(t, err, "invalid edit state: package name, edit ID and track are required")
+
+	_, err = readPersistedEdit(filepath.Join(t.TempDir(), editStateFileName))
+	assert.Error(t, err)
+}
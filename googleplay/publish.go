@@ -1,7 +1,7 @@
-package main
+package googleplay
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -20,11 +20,8 @@ const (
 	releaseStatusHalted     = "halted"
 )
 
-const bundleInstallationWarning = "Error 403: The installation of the app bundle may be too large " +
-	"and trigger user warning on some devices, and this needs to be explicitly acknowledged in the request."
-
 // uploadExpansionFiles uploads the expansion files for given applications, like .obb files.
-func (p *Publisher) uploadExpansionFiles(service *androidpublisher.Service, expFileEntry string, packageName string, appEditID string, versionCode int64) error {
+func (p *Publisher) uploadExpansionFiles(ctx context.Context, service *androidpublisher.Service, expFileEntry string, packageName string, appEditID string, versionCode int64) error {
 	cleanExpFileConfigEntry := strings.TrimSpace(expFileEntry)
 	if !validateExpansionFileConfig(cleanExpFileConfigEntry) {
 		return fmt.Errorf("invalid expansion file config: %s", expFileEntry)
@@ -36,14 +33,16 @@ func (p *Publisher) uploadExpansionFiles(service *androidpublisher.Service, expF
 	}
 	expansionFile, err := os.Open(expFilePth)
 	if err != nil {
-		return fmt.Errorf("failed to read expansion file (%v), error: %s", expansionFile, err)
+		return fmt.Errorf("failed to read expansion file (%v), error: %w", expansionFile, err)
 	}
 	p.logger.Debugf("Uploading expansion file %v with package name '%v', AppEditId '%v', version code '%v'", expansionFile, packageName, appEditID, versionCode)
 	editsExpansionFilesService := androidpublisher.NewEditsExpansionfilesService(service)
 	editsExpansionFilesCall := editsExpansionFilesService.Upload(packageName, appEditID, versionCode, expFileType)
 	editsExpansionFilesCall.Media(expansionFile, googleapi.ContentType("application/octet-stream"))
-	if _, err := editsExpansionFilesCall.Do(); err != nil {
-		return fmt.Errorf("failed to upload expansion file, error: %s", err)
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	if _, err := editsExpansionFilesCall.Context(callCtx).Do(); err != nil {
+		return fmt.Errorf("failed to upload expansion file, error: %w", err)
 	}
 	p.logger.Infof("Uploaded expansion file %v", expansionFile)
 	return nil
@@ -75,19 +74,21 @@ func validateExpansionFileConfig(expFileEntry string) bool {
 }
 
 // uploadMappingFile uploads a given mapping file to a given app artifact (based on versionCode) to Google Play.
-func (p *Publisher) uploadMappingFile(service *androidpublisher.Service, appEditID string, versionCode int64, packageName string, filePath string) error {
+func (p *Publisher) uploadMappingFile(ctx context.Context, service *androidpublisher.Service, appEditID string, versionCode int64, packageName string, filePath string) error {
 	p.logger.Debugf("Getting mapping file from %v", filePath)
 	mappingFile, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read mapping file (%s), error: %s", filePath, err)
+		return fmt.Errorf("failed to read mapping file (%s), error: %w", filePath, err)
 	}
 	p.logger.Debugf("Uploading mapping file %v with package name '%v', AppEditId '%v', version code '%v'", filePath, packageName, appEditID, versionCode)
 	editsDeobfuscationFilesService := androidpublisher.NewEditsDeobfuscationfilesService(service)
 	editsDeobfuscationFilesUploadCall := editsDeobfuscationFilesService.Upload(packageName, appEditID, versionCode, "proguard")
 	editsDeobfuscationFilesUploadCall.Media(mappingFile, googleapi.ContentType("application/octet-stream"))
 
-	if _, err = editsDeobfuscationFilesUploadCall.Do(); err != nil {
-		return fmt.Errorf("failed to upload mapping file, error: %s", err)
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	if _, err = editsDeobfuscationFilesUploadCall.Context(callCtx).Do(); err != nil {
+		return fmt.Errorf("failed to upload mapping file, error: %w", err)
 	}
 
 	p.logger.Printf(" uploaded mapping file for apk version: %d", versionCode)
@@ -95,37 +96,40 @@ func (p *Publisher) uploadMappingFile(service *androidpublisher.Service, appEdit
 }
 
 // uploadAppBundle uploads aab files to Google Play. Returns the uploaded bundle itself or an error.
-func (p *Publisher) uploadAppBundle(service *androidpublisher.Service, packageName string, appEditID string, appFile *os.File, ackBundleInstallationWarning bool) (*androidpublisher.Bundle, error) {
+func (p *Publisher) uploadAppBundle(ctx context.Context, service *androidpublisher.Service, packageName string, appEditID string, appFile *os.File, ackBundleInstallationWarning bool, deviceTierConfigID string) (*androidpublisher.Bundle, error) {
 	p.logger.Debugf("Uploading file %v with package name '%v', AppEditId '%v", appFile, packageName, appEditID)
 	editsBundlesService := androidpublisher.NewEditsBundlesService(service)
 
 	editsBundlesUploadCall := editsBundlesService.Upload(packageName, appEditID)
 	editsBundlesUploadCall.Media(appFile, googleapi.ContentType("application/octet-stream"))
 	editsBundlesUploadCall.AckBundleInstallationWarning(ackBundleInstallationWarning)
+	if deviceTierConfigID != "" {
+		editsBundlesUploadCall.DeviceTierConfigId(deviceTierConfigID)
+	}
 
-	bundle, err := editsBundlesUploadCall.Do()
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	bundle, err := editsBundlesUploadCall.Context(callCtx).Do()
 	if err != nil {
-		msg := fmt.Sprintf("failed to upload app bundle, error: %s", err)
-		if strings.Contains(err.Error(), bundleInstallationWarning) {
-			msg = fmt.Sprintf("%s\nTo acknowledge this warning, set the Acknowledge Bundle Installation Warning (ack_bundle_installation_warning) input to true.", msg)
-		}
-		return nil, errors.New(msg)
+		return nil, fmt.Errorf("failed to upload app bundle, error: %w", err)
 	}
 	p.logger.Infof("Uploaded app bundle version: %d", bundle.VersionCode)
 	return bundle, nil
 }
 
 // uploadAppApk uploads an apk file to Google Play. Returns the apk itself or an error.
-func (p *Publisher) uploadAppApk(service *androidpublisher.Service, packageName string, appEditID string, appFile *os.File) (*androidpublisher.Apk, error) {
+func (p *Publisher) uploadAppApk(ctx context.Context, service *androidpublisher.Service, packageName string, appEditID string, appFile *os.File) (*androidpublisher.Apk, error) {
 	p.logger.Debugf("Uploading file %v with package name '%v', AppEditId '%v", appFile, packageName, appEditID)
 	editsApksService := androidpublisher.NewEditsApksService(service)
 
 	editsApksUploadCall := editsApksService.Upload(packageName, appEditID)
 	editsApksUploadCall.Media(appFile, googleapi.ContentType("application/vnd.android.package-archive"))
 
-	apk, err := editsApksUploadCall.Do()
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	apk, err := editsApksUploadCall.Context(callCtx).Do()
 	if err != nil {
-		return nil, fmt.Errorf("failed to upload apk, error: %s", err)
+		return nil, fmt.Errorf("failed to upload apk, error: %w", err)
 	}
 	p.logger.Infof("Uploaded apk version: %d", apk.VersionCode)
 	return apk, nil
@@ -140,7 +144,7 @@ func (p *Publisher) updateListing(whatsNewsDir string, release *androidpublisher
 
 		recentChangesMap, err := p.readLocalisedRecentChanges(whatsNewsDir)
 		if err != nil {
-			return fmt.Errorf("failed to read whatsnews, error: %s", err)
+			return fmt.Errorf("failed to read whatsnews, error: %w", err)
 		}
 
 		var releaseNotes []*androidpublisher.LocalizedText
@@ -218,7 +222,7 @@ func (p *Publisher) createTrackRelease(config Configs, versionCodes googleapi.In
 	}
 
 	if err := p.updateListing(config.WhatsnewsDir, newRelease); err != nil {
-		return nil, fmt.Errorf("failed to update listing, reason: %v", err)
+		return nil, fmt.Errorf("failed to update listing, reason: %w", err)
 	}
 
 	return newRelease, nil
@@ -0,0 +1,594 @@
+// Package googleplay publishes Android apps to Google Play with the Google Play Developer API. It implements the
+// Google Play Deploy step, and can be embedded by other release tools: create a Publisher with New, a service with
+// Publisher.NewService, then run the configured deployment with Publisher.Deploy.
+package googleplay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+// Publisher handles publishing to Google Play with integrated logging
+type Publisher struct {
+	logger     log.Logger
+	httpClient *http.Client
+	outputs    OutputSink
+}
+
+// OutputSink receives the outputs of a deployment one by one, like the step exporting them as environment variables.
+type OutputSink func(key, value string) error
+
+// Options configures a Publisher.
+type Options struct {
+	// Logger logs the progress of the deployment. Defaults to a logger printing to the standard output.
+	Logger log.Logger
+	// HTTPClient is the client the Google Play Developer API is called with. Defaults to a client authenticated with
+	// the service account of the configuration.
+	HTTPClient *http.Client
+	// Outputs receives the outputs returned by Deploy and ReportFailure, for example to export them. Defaults to
+	// discarding them.
+	Outputs OutputSink
+}
+
+// New creates a new Publisher instance with the given options
+func New(opts Options) *Publisher {
+	if opts.Logger == nil {
+		opts.Logger = log.NewLogger()
+	}
+	return &Publisher{logger: opts.Logger, httpClient: opts.HTTPClient, outputs: opts.Outputs}
+}
+
+// NewPublisher creates a new Publisher instance with the given logger
+func NewPublisher(logger log.Logger) *Publisher {
+	return New(Options{Logger: logger})
+}
+
+// uploadApplications uploads every application file (apk or aab) to the Google Play. Returns the uploaded apps with
+// their version codes.
+func (p *Publisher) uploadApplications(ctx context.Context, configs Configs, service *androidpublisher.Service, appEdit *androidpublisher.AppEdit) ([]UploadedArtifact, error) {
+	appPaths, _ := configs.appPaths()
+	mappingPaths := configs.mappingPaths()
+	var artifacts []UploadedArtifact
+
+	var versionCodeListLog bytes.Buffer
+	versionCodeListLog.WriteString("New version codes to upload: ")
+
+	expansionFilePaths, err := configs.expansionFiles(appPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	var deviceTierConfigID string
+	if configs.DeviceTierConfigPath != "" && len(appPaths) > 0 && strings.ToLower(filepath.Ext(appPaths[0])) == ".aab" {
+		deviceTierConfigID, err = p.ensureDeviceTierConfig(ctx, service, configs.PackageName, configs.DeviceTierConfigPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for appIndex, appPath := range appPaths {
+		p.logger.Printf("Uploading %v %d/%d", appPath, appIndex+1, len(appPaths))
+		versionCode := int64(0)
+		appFile, err := os.Open(appPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open app (%s), error: %w", appPath, err)
+		}
+		artifact := UploadedArtifact{Path: appPath}
+		if info, err := appFile.Stat(); err == nil {
+			artifact.SizeBytes = info.Size()
+		}
+		uploadStart := time.Now()
+
+		if strings.ToLower(filepath.Ext(appPath)) == ".aab" {
+			bundle, err := p.uploadAppBundle(ctx, service, configs.PackageName, appEdit.Id, appFile, configs.AckBundleInstallationWarning, deviceTierConfigID)
+			if err != nil {
+				return nil, err
+			}
+			versionCode = bundle.VersionCode
+			artifact.SHA256 = bundle.Sha256
+		} else {
+			apk, err := p.uploadAppApk(ctx, service, configs.PackageName, appEdit.Id, appFile)
+			if err != nil {
+				return nil, err
+			}
+			versionCode = apk.VersionCode
+			if apk.Binary != nil {
+				artifact.SHA256 = apk.Binary.Sha256
+			}
+
+			if len(expansionFilePaths) > 0 {
+				if err := p.uploadExpansionFiles(ctx, service, expansionFilePaths[appIndex], configs.PackageName, appEdit.Id, versionCode); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// Upload mapping.txt files
+		if len(mappingPaths)-1 >= appIndex && versionCode != 0 {
+			filePath := mappingPaths[appIndex]
+			if err := p.uploadMappingFile(ctx, service, appEdit.Id, versionCode, configs.PackageName, filePath); err != nil {
+				return nil, err
+			}
+			if appIndex < len(appPaths)-1 {
+				fmt.Println()
+			}
+		}
+
+		artifact.VersionCode = versionCode
+		artifact.DurationSeconds = secondsSince(uploadStart)
+		artifacts = append(artifacts, artifact)
+		versionCodeListLog.WriteString(fmt.Sprintf("%d", versionCode))
+		if appIndex < len(appPaths)-1 {
+			versionCodeListLog.WriteString(", ")
+		}
+	}
+	p.logger.Printf("Done uploading of %v apps", len(appPaths))
+	p.logger.Printf(versionCodeListLog.String())
+	return artifacts, nil
+}
+
+// updateTracks updates the given track with a new release with the given version codes. Returns the release as
+// updated by Google Play.
+func (p *Publisher) updateTracks(ctx context.Context, configs Configs, service *androidpublisher.Service, appEdit *androidpublisher.AppEdit, versionCodes []int64) (*androidpublisher.TrackRelease, error) {
+	editsTracksService := androidpublisher.NewEditsTracksService(service)
+
+	newRelease, err := p.createTrackRelease(configs, versionCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Note we get error if we creating multiple instances of a release with the Completed status.
+	// Example: "error: googleapi: Error 400: Too many completed releases specified., releasesTooManyCompletedReleases".
+	// Also receiving error when deploying a Completed release when a rollout is in progress:
+	// error: googleapi: Error 403: You cannot rollout this release because it does not allow any existing users to upgrade
+	// to the newly added APKs., ReleaseValidationErrorKeyApkNoUpgradePaths
+
+	// inProgress preserves complete release even if not specified in releases array.
+	// In case only a completed release specified, it halts inProgress releases.
+
+	p.logger.Infof("%s track will be updated.", configs.Track)
+	editsTracksUpdateCall := editsTracksService.Update(configs.PackageName, appEdit.Id, configs.Track, &androidpublisher.Track{
+		Track:    configs.Track,
+		Releases: []*androidpublisher.TrackRelease{newRelease},
+	})
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	track, err := editsTracksUpdateCall.Context(callCtx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("update call failed, error: %w", err)
+	}
+
+	p.logger.Printf(" updated track: %s", track.Track)
+	if release := releaseWithVersionCodes(track, versionCodes); release != nil {
+		return release, nil
+	}
+	return newRelease, nil
+}
+
+// validateEdit checks that the edit can be committed, without committing it.
+func (p *Publisher) validateEdit(ctx context.Context, service *androidpublisher.Service, packageName string, editID string) error {
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	_, err := androidpublisher.NewEditsService(service).Validate(packageName, editID).Context(callCtx).Do()
+	return err
+}
+
+// commitEdit commits the edit, sending the changes for review unless changesNotSentForReview is set.
+func (p *Publisher) commitEdit(ctx context.Context, service *androidpublisher.Service, packageName string, editID string, changesNotSentForReview bool) error {
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	editsCommitCall := androidpublisher.NewEditsService(service).Commit(packageName, editID)
+	editsCommitCall.ChangesNotSentForReview(changesNotSentForReview)
+	_, err := editsCommitCall.Context(callCtx).Do()
+	return err
+}
+
+// listTracks lists the available tracks for an app. Returns the names of the tracks, or nil if they could not be listed.
+func (p *Publisher) listTracks(ctx context.Context, configs Configs, service *androidpublisher.Service, appEdit *androidpublisher.AppEdit) []string {
+	editsTracksService := androidpublisher.NewEditsTracksService(service)
+	listTracksCall := editsTracksService.List(configs.PackageName, appEdit.Id)
+
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	tracks, err := listTracksCall.Context(callCtx).Do()
+	if err != nil {
+		p.logger.Warnf("Unable to fetch track list, error: %s", err)
+		return nil
+	}
+
+	var names []string
+	for _, track := range tracks.Tracks {
+		p.logger.Printf("- %s", track.Track)
+		names = append(names, track.Track)
+	}
+	return names
+}
+
+// uploadedVersionCodes returns the distinct version codes of the uploaded apps.
+func (p *Publisher) uploadedVersionCodes(artifacts []UploadedArtifact) []int64 {
+	codeMap := map[int64]int{}
+	for _, artifact := range artifacts {
+		codeMap[artifact.VersionCode]++
+	}
+
+	var versionCodes []int64
+	for code, numArtifacts := range codeMap {
+		if numArtifacts > 1 {
+			p.logger.Warnf("There were %d artifacts uploaded for version code %d. Duplicate version codes could cause unexpected results.", numArtifacts, code)
+		}
+		versionCodes = append(versionCodes, code)
+	}
+
+	return versionCodes
+}
+
+// NewService creates the Google Play Developer API service of the configuration. It uses the HTTP client of the
+// options if set, the in-memory emulator in simulate mode, or a client authenticated with the service account of the
+// configuration otherwise.
+func (p *Publisher) NewService(ctx context.Context, configs Configs) (*androidpublisher.Service, error) {
+	client := p.httpClient
+	switch {
+	case client != nil:
+	case configs.Simulate:
+		p.logger.Warnf("Simulate mode: the requests are sent to an in-memory emulator, nothing is sent to Google Play")
+		client = p.createSimulatedClient(configs)
+	default:
+		var err error
+		client, err = p.createHTTPClient(configs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP client, error: %w", err)
+		}
+	}
+
+	service, err := p.createService(ctx, configs, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create publisher service, error: %w", err)
+	}
+	return service, nil
+}
+
+// Deploy runs the configured mode with the given service: deploys the apps to the track, persists or commits an edit,
+// uploads to internal app sharing, generates a system APK or prints the status of the tracks. The deployment is
+// cancelled with the given context, and after the configured deployment timeout.
+//
+// Returns the outputs of the deployment, which the step exports as environment variables, and sends them to the output
+// sink of the options. The outputs known before a failure, like the path of the deployment report, are returned with
+// the error.
+func (p *Publisher) Deploy(ctx context.Context, service *androidpublisher.Service, configs Configs) (Outputs, error) {
+	outputs, err := p.deploy(ctx, service, configs)
+	p.sendOutputs(outputs)
+	return outputs, err
+}
+
+func (p *Publisher) deploy(ctx context.Context, service *androidpublisher.Service, configs Configs) (Outputs, error) {
+	configs = p.withLogger(configs)
+	ctx, cancel := deploymentContext(ctx, configs)
+	defer cancel()
+
+	switch configs.Mode {
+	case ModeSystemApks:
+		fmt.Println()
+		p.logger.Infof("Generate system APK")
+		outputs, err := p.generateSystemApk(ctx, configs, service)
+		if err != nil {
+			return nil, interruptionError(ctx, "Generate system APK", fmt.Errorf("Failed to generate system APK: %w", err))
+		}
+		p.logger.Donef("System APK generated")
+		return outputs, nil
+	case ModeInternalAppSharing:
+		fmt.Println()
+		p.logger.Infof("Upload to internal app sharing")
+		outputs, err := p.uploadToInternalAppSharing(ctx, configs, service)
+		if err != nil {
+			return nil, interruptionError(ctx, "Upload to internal app sharing", fmt.Errorf("Failed to upload to internal app sharing: %w", err))
+		}
+		p.logger.Donef("Applications uploaded to internal app sharing")
+		return outputs, nil
+	case ModeStatus:
+		fmt.Println()
+		p.logger.Infof("Status of the tracks")
+		outputs, err := p.printStatus(ctx, service, configs)
+		if err != nil {
+			return nil, interruptionError(ctx, "Status of the tracks", fmt.Errorf("Failed to fetch the status of the tracks: %w", err))
+		}
+		p.logger.Donef("Status of the tracks fetched")
+		return outputs, nil
+	}
+
+	execute := p.executeEdit
+	if configs.Mode == ModeCommitEdit {
+		execute = p.commitPersistedEdit
+	}
+
+	outputs := Outputs{}
+	err := execute(ctx, service, configs, outputs, false, configs.DryRun)
+	if err != nil && ClassifyError(err).Code == FailureChangesNotSentForReview && configs.RetryWithoutSendingToReview {
+		p.logger.Warnf(err.Error())
+		p.logger.Warnf("Trying to commit edit with setting changesNotSentForReview to true. Please make sure to send the changes to review from Google Play Console UI.")
+		err = execute(ctx, service, configs, outputs, true, false)
+	}
+	return outputs, err
+}
+
+// sendOutputs sends the outputs to the output sink of the options, if any. Failing to send an output is only logged.
+func (p *Publisher) sendOutputs(outputs Outputs) {
+	if p.outputs == nil {
+		return
+	}
+	for _, key := range outputs.Keys() {
+		if err := p.outputs(key, outputs[key]); err != nil {
+			p.logger.Warnf("Unable to export %s, error: %s", key, err)
+		}
+	}
+}
+
+// UploadApplications uploads the apps of the configuration, with their expansion and mapping files, to the given edit.
+// Returns the uploaded apps with their version codes.
+func (p *Publisher) UploadApplications(ctx context.Context, service *androidpublisher.Service, configs Configs, editID string) ([]UploadedArtifact, error) {
+	ctx, cancel := deploymentContext(ctx, configs)
+	defer cancel()
+	return p.uploadApplications(ctx, p.withLogger(configs), service, &androidpublisher.AppEdit{Id: editID})
+}
+
+// UpdateTrack updates the configured track of the given edit with a new release of the given version codes. Returns
+// the release as updated by Google Play.
+func (p *Publisher) UpdateTrack(ctx context.Context, service *androidpublisher.Service, configs Configs, editID string, versionCodes []int64) (*androidpublisher.TrackRelease, error) {
+	ctx, cancel := deploymentContext(ctx, configs)
+	defer cancel()
+	return p.updateTracks(ctx, p.withLogger(configs), service, &androidpublisher.AppEdit{Id: editID}, versionCodes)
+}
+
+// withLogger returns the configuration logging with the logger of the Publisher, unless it has its own logger.
+func (p *Publisher) withLogger(configs Configs) Configs {
+	if configs.Logger == nil {
+		configs.Logger = p.logger
+	}
+	return configs
+}
+
+// executeEdit runs the edit flow, and runs it again with a new edit if it failed with a transient server error or
+// because the edit was deleted or expired. An attempt which committed its edit is never run again, as it would release
+// the apps twice. The attempts are recorded in the deployment report. The outputs of the deployment, including the
+// attempts, are added to the given ones.
+func (p *Publisher) executeEdit(ctx context.Context, service *androidpublisher.Service, configs Configs, outputs Outputs, changesNotSentForReview bool, dryRun bool) (err error) {
+	report := newDeploymentReport(configs, dryRun)
+	defer func() {
+		report.finish(err)
+		outputs.add(p.reportEditAttempts(report.Attempts))
+		reportOutputs, reportErr := p.writeDeploymentReport(configs, report)
+		if reportErr != nil {
+			p.logger.Warnf("Unable to write deployment report, error: %s", reportErr)
+		}
+		outputs.add(reportOutputs)
+	}()
+
+	for attempt := 1; ; attempt++ {
+		report.startAttempt()
+		var committed bool
+		committed, err = p.executeEditAttempt(ctx, service, configs, outputs, changesNotSentForReview, dryRun, report)
+		report.endAttempt(err)
+		if err == nil || committed || attempt > configs.EditRetries || ctx.Err() != nil || !isRetryableEditFailure(err) {
+			return err
+		}
+
+		wait := editRetryWait * time.Duration(1<<(attempt-1))
+		fmt.Println()
+		p.logger.Warnf("Attempt %d of the edit failed: %s", attempt, err)
+		p.logger.Warnf("Running the edit again with a new edit in %s (retry %d/%d)", wait, attempt, configs.EditRetries)
+		if sleepErr := sleepContext(ctx, wait); sleepErr != nil {
+			return interruptionError(ctx, "Wait before retrying the edit", err)
+		}
+	}
+}
+
+// executeEditAttempt runs the edit flow once: creates an edit, uploads the apps, updates the track and commits.
+// Returns whether the edit was committed, even if a later phase failed.
+func (p *Publisher) executeEditAttempt(ctx context.Context, service *androidpublisher.Service, configs Configs, outputs Outputs, changesNotSentForReview bool, dryRun bool, report *deploymentReport) (committed bool, err error) {
+	editsService := androidpublisher.NewEditsService(service)
+
+	// The open edit is deleted if the deployment fails, unless keeping it was requested, and the error tells in which
+	// phase the deployment was cancelled or timed out. The phases are recorded in the deployment report.
+	var appEdit *androidpublisher.AppEdit
+	report.startPhase("Create new edit")
+	defer func() {
+		if err != nil {
+			if appEdit != nil && !committed {
+				p.handleFailedEdit(service, configs, appEdit, report.phase(), err)
+			}
+			err = interruptionError(ctx, report.phase(), err)
+		}
+		if appEdit != nil {
+			p.forgetOpenEdit(configs.PackageName)
+		}
+	}()
+
+	//
+	// Delete the edit a previous run left open
+	p.cleanUpOrphanedEdit(service, configs.PackageName)
+
+	//
+	// Create insert edit
+	fmt.Println()
+	p.logger.Infof("Create new edit")
+	insertCtx, cancelInsert := callContext(ctx)
+	defer cancelInsert()
+	editsInsertCall := editsService.Insert(configs.PackageName, &androidpublisher.AppEdit{})
+	appEdit, err = editsInsertCall.Context(insertCtx).Do()
+	if err != nil {
+		return false, fmt.Errorf("Failed to perform edit insert call, error: %w", err)
+	}
+	p.logger.Printf(" editID: %s", appEdit.Id)
+	report.setEditID(appEdit.Id)
+	p.rememberOpenEdit(configs.PackageName, appEdit)
+	p.logger.Donef("Edit insert created")
+
+	//
+	// List tracks that are available in the Play Store
+	fmt.Println()
+	report.startPhase("List tracks")
+	p.logger.Infof("Available tracks on Google Play:")
+	trackNames := p.listTracks(ctx, configs, service, appEdit)
+	if err := validateTrack(configs.Track, trackNames); err != nil {
+		return false, err
+	}
+	p.logger.Donef("Tracks listed")
+
+	var stateBefore editState
+	if dryRun {
+		//
+		// Fetch the state to compare the edit against
+		fmt.Println()
+		report.startPhase("Fetch current state")
+		p.logger.Infof("Dry run: fetching current state of the app")
+		stateBefore, err = p.fetchEditState(ctx, configs, service, appEdit)
+		if err != nil {
+			return false, fmt.Errorf("Failed to fetch current state of the app, error: %w", err)
+		}
+		p.logger.Donef("Current state fetched")
+	}
+
+	//
+	// Upload applications
+	fmt.Println()
+	report.startPhase("Upload apks or app bundles")
+	p.logger.Infof("Upload apks or app bundles")
+	artifacts, err := p.uploadApplications(ctx, configs, service, appEdit)
+	if err != nil {
+		return false, fmt.Errorf("Failed to upload application(s): %w", err)
+	}
+	report.addArtifacts(artifacts)
+	p.logger.Donef("Applications uploaded")
+
+	// Update track
+	fmt.Println()
+	report.startPhase("Update track")
+	p.logger.Infof("Update track")
+	versionCodeSlice := p.uploadedVersionCodes(artifacts)
+	release, err := p.updateTracks(ctx, configs, service, appEdit, versionCodeSlice)
+	if err != nil {
+		return false, fmt.Errorf("Failed to update track, reason: %w", err)
+	}
+	p.logger.Donef("Track updated")
+
+	if configs.TestersGoogleGroups != "" {
+		//
+		// Update testers
+		fmt.Println()
+		report.startPhase("Update testers")
+		p.logger.Infof("Update testers")
+		if err := p.updateTesters(ctx, configs, service, appEdit); err != nil {
+			return false, fmt.Errorf("Failed to update testers, reason: %w", err)
+		}
+		p.logger.Donef("Testers updated")
+	}
+
+	//
+	// Report country availability
+	fmt.Println()
+	report.startPhase("Report country availability")
+	p.logger.Infof("Country availability of the %s track", configs.Track)
+	if countryOutputs, err := p.reportCountryAvailability(ctx, configs, service, appEdit); err != nil {
+		p.logger.Warnf("Unable to report country availability, error: %s", err)
+		report.warn(err)
+	} else {
+		outputs.add(countryOutputs)
+		p.logger.Donef("Country availability reported")
+	}
+
+	if dryRun {
+		//
+		// Print the changes of the edit
+		fmt.Println()
+		report.startPhase("Fetch state of the edit")
+		p.logger.Infof("Dry run: changes the edit would make")
+		stateAfter, err := p.fetchEditState(ctx, configs, service, appEdit)
+		if err != nil {
+			return false, fmt.Errorf("Failed to fetch state of the edit, error: %w", err)
+		}
+		diff := diffEditStates(stateBefore, stateAfter)
+		p.printEditDiff(diff)
+		diffOutputs, err := p.exportEditDiff(configs.DeployDir, diff)
+		if err != nil {
+			p.logger.Warnf("Unable to export dry run diff, error: %s", err)
+			report.warn(err)
+		}
+		outputs.add(diffOutputs)
+
+		//
+		// Validate edit
+		fmt.Println()
+		report.startPhase("Validate edit")
+		p.logger.Infof("Dry run: validating edit without committing")
+		if err := p.validateEdit(ctx, service, configs.PackageName, appEdit.Id); err != nil {
+			return false, fmt.Errorf("Failed to validate edit, error: %w", err)
+		}
+		p.logger.Donef("Edit validated")
+	} else if configs.Mode == ModeUploadOnly {
+		//
+		// Validate edit
+		fmt.Println()
+		report.startPhase("Validate edit")
+		p.logger.Infof("Validating edit")
+		if err := p.validateEdit(ctx, service, configs.PackageName, appEdit.Id); err != nil {
+			return false, fmt.Errorf("Failed to validate edit, error: %w", err)
+		}
+		p.logger.Donef("Edit validated")
+
+		//
+		// Persist edit
+		fmt.Println()
+		report.startPhase("Persist edit")
+		p.logger.Infof("Persist edit without committing")
+		edit := newPersistedEdit(configs, appEdit, versionCodeSlice, artifactHashes(artifacts))
+		editOutputs, err := p.persistEdit(configs.DeployDir, edit)
+		if err != nil {
+			return false, fmt.Errorf("Failed to persist edit, error: %w", err)
+		}
+		outputs.add(editOutputs)
+		p.logger.Donef("Edit persisted, commit it with the %s mode", ModeCommitEdit)
+	} else {
+		//
+		// Commit edit
+		fmt.Println()
+		report.startPhase("Commit edit")
+		p.logger.Infof("Committing edit")
+		if err := p.commitEdit(ctx, service, configs.PackageName, appEdit.Id, changesNotSentForReview); err != nil {
+			return false, fmt.Errorf("Failed to commit edit, error: %w", err)
+		}
+		committed = true
+		p.logger.Donef("Edit committed")
+
+		result := deployment{
+			EditID:          appEdit.Id,
+			Track:           configs.Track,
+			VersionCodes:    versionCodeSlice,
+			ArtifactSHA256s: artifactHashes(artifacts),
+			Release:         release,
+		}
+		outputs.add(result.outputs())
+
+		if configs.shouldDownloadGeneratedApks() {
+			//
+			// Download generated APKs
+			fmt.Println()
+			report.startPhase("Download generated APKs")
+			p.logger.Infof("Download generated APKs")
+			apkOutputs, err := p.downloadGeneratedApks(ctx, configs, service, versionCodeSlice)
+			if err != nil {
+				return true, fmt.Errorf("Failed to download generated APKs, error: %w", err)
+			}
+			outputs.add(apkOutputs)
+			p.logger.Donef("Generated APKs downloaded")
+		}
+	}
+	return committed, nil
+}
@@ -0,0 +1,319 @@
+package googleplay
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-google-play-deploy/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/androidpublisher/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// TestMain records the open edits of the tests in a temporary directory, instead of the one of the real runs.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "open-edits")
+	if err != nil {
+		panic(err)
+	}
+	openEditsDir = dir
+	code := m.Run()
+	if err := os.RemoveAll(dir); err != nil {
+		panic(err)
+	}
+	os.Exit(code)
+}
+
+// newEmulatedPublisher creates an emulated Google Play with the app of the configuration, and a Publisher with its
+// service talking to it. The open edits of the test are recorded in its own temporary directory.
+func newEmulatedPublisher(t *testing.T, configs Configs, opts ...emulator.AppOption) (*emulator.Server, *Publisher, *androidpublisher.Service) {
+	t.Helper()
+	withOpenEditsDir(t)
+
+	server := emulator.NewServer()
+	server.AddApp(configs.PackageName, opts...)
+
+	publisher := New(Options{HTTPClient: server.Client()})
+	service, err := publisher.NewService(context.Background(), configs)
+	require.NoError(t, err)
+	return server, publisher, service
+}
+
+// withOpenEditsDir records the open edits of the test in its own temporary directory.
+func withOpenEditsDir(t *testing.T) {
+	original := openEditsDir
+	openEditsDir = t.TempDir()
+	t.Cleanup(func() {
+		openEditsDir = original
+	})
+}
+
+func TestParseURI(t *testing.T) {
+
+	t.Log("parseURI - file://../../../../../../Downloads/key.json")
+	{
+		keyPth, isRemote, err := parseURI("file://../../../../../../Downloads/key.json")
+		require.NoError(t, err)
+
+		require.Equal(t, "../../../../../../Downloads/key.json", keyPth)
+		require.Equal(t, false, isRemote)
+	}
+
+	t.Log("parseURI - file://./")
+	{
+		keyPth, isRemote, err := parseURI("file://./testfolder/key.json")
+		require.NoError(t, err)
+
+		require.Equal(t, "./testfolder/key.json", keyPth)
+		require.Equal(t, false, isRemote)
+	}
+
+	t.Log("parseURI - file:///")
+	{
+		keyPth, isRemote, err := parseURI("file:///testfolder/key.json")
+		require.NoError(t, err)
+
+		require.Equal(t, "/testfolder/key.json", keyPth)
+		require.Equal(t, false, isRemote)
+	}
+
+	t.Log("parseURI - http://")
+	{
+		keyPth, isRemote, err := parseURI("http://testdomain.com/testsub/key.json")
+		require.NoError(t, err)
+
+		require.Equal(t, "http://testdomain.com/testsub/key.json", keyPth)
+		require.Equal(t, true, isRemote)
+	}
+
+	t.Log("parseURI - https://")
+	{
+		keyPth, isRemote, err := parseURI("https://testdomain.com/testsub/key.json")
+		require.NoError(t, err)
+
+		require.Equal(t, "https://testdomain.com/testsub/key.json", keyPth)
+		require.Equal(t, true, isRemote)
+	}
+
+	t.Log("parseURI - gs://")
+	{
+		keyPth, isRemote, err := parseURI("gs://testbucket/testsub/key.json")
+		require.NoError(t, err)
+
+		require.Equal(t, "gs://testbucket/testsub/key.json", keyPth)
+		require.Equal(t, true, isRemote)
+	}
+
+	t.Log("parseURI - s3://")
+	{
+		keyPth, isRemote, err := parseURI("s3://testbucket/testsub/key.json")
+		require.NoError(t, err)
+
+		require.Equal(t, "s3://testbucket/testsub/key.json", keyPth)
+		require.Equal(t, true, isRemote)
+	}
+
+	t.Log("parseURI - ./")
+	{
+		keyPth, isRemote, err := parseURI("./user/test/key.json")
+		require.NoError(t, err)
+
+		require.Equal(t, "./user/test/key.json", keyPth)
+		require.Equal(t, false, isRemote)
+	}
+
+	t.Log("parseURI - /")
+	{
+		keyPth, isRemote, err := parseURI("/user/test/key.json")
+		require.NoError(t, err)
+
+		require.Equal(t, "/user/test/key.json", keyPth)
+		require.Equal(t, false, isRemote)
+	}
+}
+
+func writeBundles(t *testing.T, versionCodes ...string) string {
+	dir := t.TempDir()
+	var pths []string
+	for _, versionCode := range versionCodes {
+		pth := filepath.Join(dir, "app-"+versionCode+".aab")
+		require.NoError(t, os.WriteFile(pth, []byte("versionCode="+versionCode), 0600))
+		pths = append(pths, pth)
+	}
+	return strings.Join(pths, "\n")
+}
+
+func TestPublisher_executeEdit(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	existingRelease := androidpublisher.TrackRelease{Status: releaseStatusCompleted, VersionCodes: []int64{1}}
+
+	tests := []struct {
+		name                    string
+		appOpts                 []emulator.AppOption
+		configs                 Configs
+		changesNotSentForReview bool
+		dryRun                  bool
+		wantErr                 string
+		wantCode                FailureCode
+		wantReleases            []*androidpublisher.TrackRelease
+	}{
+		{
+			name:    "completed release",
+			appOpts: []emulator.AppOption{emulator.WithRelease("production", existingRelease)},
+			configs: Configs{AppPath: writeBundles(t, "2"), Track: "production"},
+			wantReleases: []*androidpublisher.TrackRelease{
+				{Status: releaseStatusCompleted, VersionCodes: []int64{2}},
+			},
+		},
+		{
+			name:    "staged rollout keeps the completed release",
+			appOpts: []emulator.AppOption{emulator.WithRelease("production", existingRelease)},
+			configs: Configs{AppPath: writeBundles(t, "2"), Track: "production", UserFraction: 0.2, ReleaseName: "2.0"},
+			wantReleases: []*androidpublisher.TrackRelease{
+				{Status: releaseStatusCompleted, VersionCodes: []int64{1}},
+				{Status: releaseStatusInProgress, VersionCodes: []int64{2}, UserFraction: 0.2, Name: "2.0"},
+			},
+		},
+		{
+			name:         "dry run does not commit",
+			appOpts:      []emulator.AppOption{emulator.WithRelease("production", existingRelease)},
+			configs:      Configs{AppPath: writeBundles(t, "2"), Track: "production", DeployDir: t.TempDir()},
+			dryRun:       true,
+			wantReleases: []*androidpublisher.TrackRelease{&existingRelease},
+		},
+		{
+			name:         "version code conflict",
+			appOpts:      []emulator.AppOption{emulator.WithRelease("production", existingRelease)},
+			configs:      Configs{AppPath: writeBundles(t, "1"), Track: "production"},
+			wantErr:      "Failed to upload application(s): failed to upload app bundle, error: googleapi: Error 403: APK specifies a version code that has already been used., apkUpgradeVersionConflict",
+			wantCode:     FailureVersionCodeConflict,
+			wantReleases: []*androidpublisher.TrackRelease{&existingRelease},
+		},
+		{
+			name:     "changes not sent for review",
+			appOpts:  []emulator.AppOption{emulator.RequireChangesNotSentForReview()},
+			configs:  Configs{AppPath: writeBundles(t, "2"), Track: "beta"},
+			wantErr:  changesNotSentForReviewMessage,
+			wantCode: FailureChangesNotSentForReview,
+		},
+		{
+			name:                    "retry without sending changes for review",
+			appOpts:                 []emulator.AppOption{emulator.RequireChangesNotSentForReview()},
+			configs:                 Configs{AppPath: writeBundles(t, "2"), Track: "beta"},
+			changesNotSentForReview: true,
+			wantReleases: []*androidpublisher.TrackRelease{
+				{Status: releaseStatusCompleted, VersionCodes: []int64{2}},
+			},
+		},
+		{
+			name:     "unknown track",
+			configs:  Configs{AppPath: writeBundles(t, "2"), Track: "qa"},
+			wantErr:  "Track not found: qa.",
+			wantCode: FailureTrackNotFound,
+		},
+		{
+			name:     "misspelled track",
+			configs:  Configs{AppPath: writeBundles(t, "2"), Track: "bta"},
+			wantErr:  "Track not found: bta. Did you mean: beta?",
+			wantCode: FailureTrackNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs := tt.configs
+			configs.PackageName = packageName
+			configs.Logger = log.NewLogger()
+			server, publisher, service := newEmulatedPublisher(t, configs, tt.appOpts...)
+
+			err := publisher.executeEdit(context.Background(), service, configs, Outputs{}, tt.changesNotSentForReview, tt.dryRun)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.Equal(t, tt.wantCode, ClassifyError(err).Code)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if tt.wantReleases != nil {
+				track := server.Track(packageName, configs.Track)
+				require.NotNil(t, track)
+				assert.Equal(t, tt.wantReleases, track.Releases)
+			}
+		})
+	}
+}
+
+func TestPublisher_Deploy(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+
+	tests := []struct {
+		name                        string
+		appOpts                     []emulator.AppOption
+		configs                     Configs
+		wantCode                    FailureCode
+		wantCommits                 int
+		retryWithoutSendingToReview bool
+	}{
+		{
+			name:        "deploys without a logger in the configuration",
+			configs:     Configs{AppPath: writeBundles(t, "2"), Track: "beta"},
+			wantCommits: 1,
+		},
+		{
+			name:     "returns the error instead of exiting",
+			configs:  Configs{AppPath: writeBundles(t, "2"), Track: "qa"},
+			wantCode: FailureTrackNotFound,
+		},
+		{
+			name:        "retries without sending changes for review",
+			appOpts:     []emulator.AppOption{emulator.RequireChangesNotSentForReview()},
+			configs:     Configs{AppPath: writeBundles(t, "2"), Track: "beta", RetryWithoutSendingToReview: true},
+			wantCommits: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs := tt.configs
+			configs.PackageName = packageName
+			server, publisher, service := newEmulatedPublisher(t, configs, tt.appOpts...)
+
+			_, err := publisher.Deploy(context.Background(), service, configs)
+			if tt.wantCode != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantCode, ClassifyError(err).Code)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCommits, server.Commits(packageName))
+		})
+	}
+}
+
+func TestPublisher_UploadApplicationsAndUpdateTrack(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	configs := Configs{PackageName: packageName, AppPath: writeBundles(t, "3"), Track: "alpha"}
+	server, publisher, service := newEmulatedPublisher(t, configs)
+
+	appEdit, err := androidpublisher.NewEditsService(service).Insert(packageName, &androidpublisher.AppEdit{}).Do()
+	require.NoError(t, err)
+
+	artifacts, err := publisher.UploadApplications(context.Background(), service, configs, appEdit.Id)
+	require.NoError(t, err)
+	require.Len(t, artifacts, 1)
+	assert.Equal(t, int64(3), artifacts[0].VersionCode)
+
+	release, err := publisher.UpdateTrack(context.Background(), service, configs, appEdit.Id, []int64{3})
+	require.NoError(t, err)
+	assert.Equal(t, googleapi.Int64s{3}, release.VersionCodes)
+
+	require.NoError(t, publisher.commitEdit(context.Background(), service, packageName, appEdit.Id, false))
+	track := server.Track(packageName, "alpha")
+	require.NotNil(t, track)
+	require.Len(t, track.Releases, 1)
+	assert.Equal(t, googleapi.Int64s{3}, track.Releases[0].VersionCodes)
+}
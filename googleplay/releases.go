@@ -0,0 +1,264 @@
+package googleplay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+// Promote releases the latest completed or staged release of the given track to the configured track, with the
+// configured status, user fraction and release name. The release notes are read from the whatsnews directory if given,
+// otherwise the release notes of the promoted release are kept. Returns the new release.
+func (p *Publisher) Promote(ctx context.Context, service *androidpublisher.Service, configs Configs, fromTrack string) (*androidpublisher.TrackRelease, error) {
+	if fromTrack == configs.Track {
+		return nil, fmt.Errorf("cannot promote a release of the %s track to itself", fromTrack)
+	}
+
+	var promoted *androidpublisher.TrackRelease
+	err := p.changeInEdit(ctx, service, configs, func(ctx context.Context, editsTracksService *androidpublisher.EditsTracksService, editID string) error {
+		source, err := p.getTrack(ctx, editsTracksService, configs.PackageName, editID, fromTrack)
+		if err != nil {
+			return err
+		}
+		release := latestRelease(source, releaseStatusCompleted, releaseStatusInProgress)
+		if release == nil {
+			return fmt.Errorf("the %s track has no completed or staged release to promote", fromTrack)
+		}
+		p.logger.Printf(" promoting release %s with version codes %v from track: %s", release.Name, release.VersionCodes, fromTrack)
+
+		newRelease, err := p.createTrackRelease(configs, release.VersionCodes)
+		if err != nil {
+			return err
+		}
+		if newRelease.Name == "" {
+			newRelease.Name = release.Name
+		}
+		if len(newRelease.ReleaseNotes) == 0 {
+			newRelease.ReleaseNotes = release.ReleaseNotes
+		}
+
+		target := &androidpublisher.Track{Track: configs.Track, Releases: []*androidpublisher.TrackRelease{newRelease}}
+		updated, err := p.putTrack(ctx, editsTracksService, configs.PackageName, editID, target)
+		if err != nil {
+			return err
+		}
+		promoted = newRelease
+		if release := releaseWithVersionCodes(updated, newRelease.VersionCodes); release != nil {
+			promoted = release
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return promoted, nil
+}
+
+// Rollout updates the user fraction of the staged rollout of the configured track, resuming it if it was halted. If the
+// configured status is completed, the rollout is completed instead, replacing the previous completed release. Returns
+// the updated release.
+func (p *Publisher) Rollout(ctx context.Context, service *androidpublisher.Service, configs Configs) (*androidpublisher.TrackRelease, error) {
+	complete := configs.Status == releaseStatusCompleted
+	if !complete && configs.UserFraction <= 0 {
+		return nil, errors.New("either a user fraction or the completed status is required to update the rollout")
+	}
+
+	return p.updateRelease(ctx, service, configs, []string{releaseStatusInProgress, releaseStatusHalted}, func(release *androidpublisher.TrackRelease) {
+		if complete {
+			p.logger.Printf(" completing the rollout of release %s", release.Name)
+			release.Status = releaseStatusCompleted
+			release.UserFraction = 0
+			return
+		}
+		p.logger.Printf(" rolling out release %s to %v of users", release.Name, configs.UserFraction)
+		release.Status = releaseStatusInProgress
+		release.UserFraction = configs.UserFraction
+	})
+}
+
+// Halt halts the staged rollout of the configured track. Returns the halted release.
+func (p *Publisher) Halt(ctx context.Context, service *androidpublisher.Service, configs Configs) (*androidpublisher.TrackRelease, error) {
+	return p.updateRelease(ctx, service, configs, []string{releaseStatusInProgress}, func(release *androidpublisher.TrackRelease) {
+		p.logger.Printf(" halting the rollout of release %s at %v of users", release.Name, release.UserFraction)
+		release.Status = releaseStatusHalted
+	})
+}
+
+// Tracks returns the tracks of the app with their releases.
+func (p *Publisher) Tracks(ctx context.Context, service *androidpublisher.Service, configs Configs) ([]*androidpublisher.Track, error) {
+	var tracks []*androidpublisher.Track
+	err := p.readInEdit(ctx, service, configs, func(ctx context.Context, editID string) error {
+		callCtx, cancel := callContext(ctx)
+		defer cancel()
+		response, err := androidpublisher.NewEditsTracksService(service).List(configs.PackageName, editID).Context(callCtx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to list tracks, error: %w", err)
+		}
+		tracks = response.Tracks
+		return nil
+	})
+	return tracks, err
+}
+
+// updateRelease changes the latest release of the configured track with one of the given statuses, and updates the
+// track with it. Other completed releases are dropped if the change completes the release.
+func (p *Publisher) updateRelease(ctx context.Context, service *androidpublisher.Service, configs Configs, statuses []string, change func(*androidpublisher.TrackRelease)) (*androidpublisher.TrackRelease, error) {
+	var updated *androidpublisher.TrackRelease
+	err := p.changeInEdit(ctx, service, configs, func(ctx context.Context, editsTracksService *androidpublisher.EditsTracksService, editID string) error {
+		track, err := p.getTrack(ctx, editsTracksService, configs.PackageName, editID, configs.Track)
+		if err != nil {
+			return err
+		}
+		release := latestRelease(track, statuses...)
+		if release == nil {
+			return fmt.Errorf("the %s track has no staged rollout", configs.Track)
+		}
+		change(release)
+
+		releases := []*androidpublisher.TrackRelease{release}
+		for _, other := range track.Releases {
+			if other == release || (release.Status == releaseStatusCompleted && other.Status == releaseStatusCompleted) {
+				continue
+			}
+			releases = append(releases, other)
+		}
+		track.Releases = releases
+
+		track, err = p.putTrack(ctx, editsTracksService, configs.PackageName, editID, track)
+		if err != nil {
+			return err
+		}
+		updated = release
+		if release := releaseWithVersionCodes(track, release.VersionCodes); release != nil {
+			updated = release
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (p *Publisher) getTrack(ctx context.Context, editsTracksService *androidpublisher.EditsTracksService, packageName, editID, track string) (*androidpublisher.Track, error) {
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	result, err := editsTracksService.Get(packageName, editID, track).Context(callCtx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get track %s, error: %w", track, err)
+	}
+	return result, nil
+}
+
+func (p *Publisher) putTrack(ctx context.Context, editsTracksService *androidpublisher.EditsTracksService, packageName, editID string, track *androidpublisher.Track) (*androidpublisher.Track, error) {
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	result, err := editsTracksService.Update(packageName, editID, track.Track, track).Context(callCtx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update track %s, error: %w", track.Track, err)
+	}
+	p.logger.Printf(" updated track: %s", result.Track)
+	return result, nil
+}
+
+// latestRelease returns the release of the track with one of the given statuses and the highest version code.
+func latestRelease(track *androidpublisher.Track, statuses ...string) *androidpublisher.TrackRelease {
+	var latest *androidpublisher.TrackRelease
+	var latestVersionCode int64 = -1
+	for _, release := range track.Releases {
+		if !containsString(statuses, release.Status) {
+			continue
+		}
+		for _, versionCode := range release.VersionCodes {
+			if versionCode > latestVersionCode {
+				latest, latestVersionCode = release, versionCode
+			}
+		}
+	}
+	return latest
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// changeInEdit runs the given change of the tracks in a new edit, then validates and commits the edit. In dry run mode
+// the edit is only validated.
+func (p *Publisher) changeInEdit(ctx context.Context, service *androidpublisher.Service, configs Configs, change func(context.Context, *androidpublisher.EditsTracksService, string) error) error {
+	return p.inEdit(ctx, service, configs, true, func(ctx context.Context, editID string) error {
+		return change(ctx, androidpublisher.NewEditsTracksService(service), editID)
+	})
+}
+
+// readInEdit runs the given reads in a new edit, which is deleted afterwards.
+func (p *Publisher) readInEdit(ctx context.Context, service *androidpublisher.Service, configs Configs, read func(context.Context, string) error) error {
+	return p.inEdit(ctx, service, configs, false, read)
+}
+
+// inEdit creates an edit and runs the given function with it, then validates and commits the edit if commit is set and
+// not in dry run mode. The edit is deleted unless it was committed.
+func (p *Publisher) inEdit(ctx context.Context, service *androidpublisher.Service, configs Configs, commit bool, run func(context.Context, string) error) (err error) {
+	configs = p.withLogger(configs)
+	ctx, cancel := deploymentContext(ctx, configs)
+	defer cancel()
+
+	insertCtx, cancelInsert := callContext(ctx)
+	defer cancelInsert()
+	appEdit, err := androidpublisher.NewEditsService(service).Insert(configs.PackageName, &androidpublisher.AppEdit{}).Context(insertCtx).Do()
+	if err != nil {
+		return fmt.Errorf("Failed to perform edit insert call, error: %w", err)
+	}
+	p.logger.Debugf("editID: %s", appEdit.Id)
+
+	committed := false
+	defer func() {
+		if err != nil {
+			err = interruptionError(ctx, "Edit", err)
+		}
+		if committed || (err != nil && ClassifyError(err).Code == FailureEditDeleted) {
+			return
+		}
+		if deleteErr := p.deleteEdit(service, configs.PackageName, appEdit.Id); deleteErr != nil {
+			p.logger.Warnf("Failed to delete edit %s, error: %s", appEdit.Id, deleteErr)
+		}
+	}()
+
+	if err := run(ctx, appEdit.Id); err != nil {
+		return err
+	}
+	if !commit {
+		return nil
+	}
+	committed, err = p.validateAndCommit(ctx, service, configs, appEdit.Id)
+	return err
+}
+
+// validateAndCommit validates the edit and commits it, unless in dry run mode. The commit is retried without sending the
+// changes for review if that is required and allowed. Returns whether the edit was committed.
+func (p *Publisher) validateAndCommit(ctx context.Context, service *androidpublisher.Service, configs Configs, editID string) (bool, error) {
+	if err := p.validateEdit(ctx, service, configs.PackageName, editID); err != nil {
+		return false, fmt.Errorf("Failed to validate edit, error: %w", err)
+	}
+	if configs.DryRun {
+		p.logger.Warnf("Dry run: the edit is validated, but not committed")
+		return false, nil
+	}
+
+	err := p.commitEdit(ctx, service, configs.PackageName, editID, false)
+	if err != nil && ClassifyError(err).Code == FailureChangesNotSentForReview && configs.RetryWithoutSendingToReview {
+		p.logger.Warnf("Committing edit with setting changesNotSentForReview to true. Please make sure to send the changes to review from Google Play Console UI.")
+		err = p.commitEdit(ctx, service, configs.PackageName, editID, true)
+	}
+	if err != nil {
+		return false, fmt.Errorf("Failed to commit edit, error: %w", err)
+	}
+	p.logger.Donef("Edit committed")
+	return true, nil
+}
@@ -0,0 +1,165 @@
+package googleplay
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-google-play-deploy/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+func TestPublisher_releaseCommands(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	completed := androidpublisher.TrackRelease{Name: "1.0", Status: releaseStatusCompleted, VersionCodes: []int64{1}}
+	staged := androidpublisher.TrackRelease{Name: "2.0", Status: releaseStatusInProgress, VersionCodes: []int64{2}, UserFraction: 0.1}
+	halted := androidpublisher.TrackRelease{Name: "2.0", Status: releaseStatusHalted, VersionCodes: []int64{2}, UserFraction: 0.1}
+	releaseNotes := []*androidpublisher.LocalizedText{{Language: "en-US", Text: "Bug fixes"}}
+
+	tests := []struct {
+		name         string
+		appOpts      []emulator.AppOption
+		configs      Configs
+		run          func(*Publisher, *androidpublisher.Service, Configs) (*androidpublisher.TrackRelease, error)
+		wantErr      string
+		wantCommits  int
+		wantReleases []*androidpublisher.TrackRelease
+	}{
+		{
+			name:    "promote keeps the release name and notes",
+			appOpts: []emulator.AppOption{emulator.WithRelease("beta", androidpublisher.TrackRelease{Name: "1.0", Status: releaseStatusCompleted, VersionCodes: []int64{1}, ReleaseNotes: releaseNotes})},
+			configs: Configs{Track: "production"},
+			run: func(p *Publisher, service *androidpublisher.Service, configs Configs) (*androidpublisher.TrackRelease, error) {
+				return p.Promote(context.Background(), service, configs, "beta")
+			},
+			wantCommits: 1,
+			wantReleases: []*androidpublisher.TrackRelease{
+				{Name: "1.0", Status: releaseStatusCompleted, VersionCodes: []int64{1}, ReleaseNotes: releaseNotes},
+			},
+		},
+		{
+			name:    "promote as a staged rollout",
+			appOpts: []emulator.AppOption{emulator.WithRelease("production", completed), emulator.WithRelease("beta", androidpublisher.TrackRelease{Name: "2.0", Status: releaseStatusCompleted, VersionCodes: []int64{2}})},
+			configs: Configs{Track: "production", UserFraction: 0.1},
+			run: func(p *Publisher, service *androidpublisher.Service, configs Configs) (*androidpublisher.TrackRelease, error) {
+				return p.Promote(context.Background(), service, configs, "beta")
+			},
+			wantCommits:  1,
+			wantReleases: []*androidpublisher.TrackRelease{&completed, &staged},
+		},
+		{
+			name:    "promote from an empty track",
+			configs: Configs{Track: "production"},
+			run: func(p *Publisher, service *androidpublisher.Service, configs Configs) (*androidpublisher.TrackRelease, error) {
+				return p.Promote(context.Background(), service, configs, "beta")
+			},
+			wantErr: "the beta track has no completed or staged release to promote",
+		},
+		{
+			name:    "rollout updates the user fraction",
+			appOpts: []emulator.AppOption{emulator.WithRelease("production", completed), emulator.WithRelease("production", staged)},
+			configs: Configs{Track: "production", UserFraction: 0.5},
+			run: func(p *Publisher, service *androidpublisher.Service, configs Configs) (*androidpublisher.TrackRelease, error) {
+				return p.Rollout(context.Background(), service, configs)
+			},
+			wantCommits: 1,
+			wantReleases: []*androidpublisher.TrackRelease{
+				{Name: "2.0", Status: releaseStatusInProgress, VersionCodes: []int64{2}, UserFraction: 0.5},
+				&completed,
+			},
+		},
+		{
+			name:    "rollout completes the release",
+			appOpts: []emulator.AppOption{emulator.WithRelease("production", completed), emulator.WithRelease("production", staged)},
+			configs: Configs{Track: "production", Status: releaseStatusCompleted},
+			run: func(p *Publisher, service *androidpublisher.Service, configs Configs) (*androidpublisher.TrackRelease, error) {
+				return p.Rollout(context.Background(), service, configs)
+			},
+			wantCommits: 1,
+			wantReleases: []*androidpublisher.TrackRelease{
+				{Name: "2.0", Status: releaseStatusCompleted, VersionCodes: []int64{2}},
+			},
+		},
+		{
+			name:    "rollout resumes a halted release",
+			appOpts: []emulator.AppOption{emulator.WithRelease("production", halted)},
+			configs: Configs{Track: "production", UserFraction: 0.2},
+			run: func(p *Publisher, service *androidpublisher.Service, configs Configs) (*androidpublisher.TrackRelease, error) {
+				return p.Rollout(context.Background(), service, configs)
+			},
+			wantCommits: 1,
+			wantReleases: []*androidpublisher.TrackRelease{
+				{Name: "2.0", Status: releaseStatusInProgress, VersionCodes: []int64{2}, UserFraction: 0.2},
+			},
+		},
+		{
+			name:    "halt",
+			appOpts: []emulator.AppOption{emulator.WithRelease("production", completed), emulator.WithRelease("production", staged)},
+			configs: Configs{Track: "production"},
+			run: func(p *Publisher, service *androidpublisher.Service, configs Configs) (*androidpublisher.TrackRelease, error) {
+				return p.Halt(context.Background(), service, configs)
+			},
+			wantCommits:  1,
+			wantReleases: []*androidpublisher.TrackRelease{&halted, &completed},
+		},
+		{
+			name:    "halt without a staged rollout",
+			appOpts: []emulator.AppOption{emulator.WithRelease("production", completed)},
+			configs: Configs{Track: "production"},
+			run: func(p *Publisher, service *androidpublisher.Service, configs Configs) (*androidpublisher.TrackRelease, error) {
+				return p.Halt(context.Background(), service, configs)
+			},
+			wantErr:      "the production track has no staged rollout",
+			wantReleases: []*androidpublisher.TrackRelease{&completed},
+		},
+		{
+			name:    "dry run does not commit",
+			appOpts: []emulator.AppOption{emulator.WithRelease("production", staged)},
+			configs: Configs{Track: "production", DryRun: true},
+			run: func(p *Publisher, service *androidpublisher.Service, configs Configs) (*androidpublisher.TrackRelease, error) {
+				return p.Halt(context.Background(), service, configs)
+			},
+			wantReleases: []*androidpublisher.TrackRelease{&staged},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs := tt.configs
+			configs.PackageName = packageName
+			server, publisher, service := newEmulatedPublisher(t, configs, tt.appOpts...)
+
+			_, err := tt.run(publisher, service, configs)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCommits, server.Commits(packageName))
+			assert.Equal(t, 0, server.OpenEdits(packageName))
+
+			if tt.wantReleases != nil {
+				track := server.Track(packageName, configs.Track)
+				require.NotNil(t, track)
+				assert.Equal(t, tt.wantReleases, track.Releases)
+			}
+		})
+	}
+}
+
+func TestPublisher_Tracks(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	configs := Configs{PackageName: packageName}
+	server, publisher, service := newEmulatedPublisher(t, configs, emulator.WithTracks("qa"))
+
+	tracks, err := publisher.Tracks(context.Background(), service, configs)
+	require.NoError(t, err)
+	var names []string
+	for _, track := range tracks {
+		names = append(names, track.Track)
+	}
+	assert.ElementsMatch(t, append([]string{"qa"}, emulator.BuiltInTracks...), names)
+	assert.Equal(t, 0, server.OpenEdits(packageName))
+	assert.Equal(t, 0, server.Commits(packageName))
+}
@@ -0,0 +1,291 @@
+package googleplay
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	deploymentReportNone         = "none"
+	deploymentReportJSON         = "json"
+	deploymentReportJSONAndJUnit = "json_and_junit"
+)
+
+const (
+	deploymentReportFileName      = "google-play-deployment-report.json"
+	deploymentReportJUnitFileName = "google-play-deployment-report.xml"
+	deploymentReportPathKey       = "GOOGLE_PLAY_DEPLOYMENT_REPORT_PATH"
+	deploymentReportJUnitPathKey  = "GOOGLE_PLAY_DEPLOYMENT_REPORT_JUNIT_PATH"
+)
+
+const (
+	outcomeRunning   = "running"
+	outcomeSucceeded = "succeeded"
+	outcomeWarning   = "warning"
+	outcomeFailed    = "failed"
+)
+
+// deploymentReport describes the attempts and phases of an edit with their timings and outcome.
+type deploymentReport struct {
+	PackageName     string         `json:"package_name"`
+	Track           string         `json:"track"`
+	EditID          string         `json:"edit_id,omitempty"`
+	DryRun          bool           `json:"dry_run"`
+	Outcome         string         `json:"outcome"`
+	FailureReason   FailureCode    `json:"failure_reason,omitempty"`
+	Error           string         `json:"error,omitempty"`
+	StartedAt       time.Time      `json:"started_at"`
+	DurationSeconds float64        `json:"duration_seconds"`
+	Attempts        []*editAttempt `json:"attempts"`
+	Phases          []*reportPhase `json:"phases"`
+}
+
+// editAttempt is a single run of the edit flow.
+type editAttempt struct {
+	Number          int         `json:"number"`
+	EditID          string      `json:"edit_id,omitempty"`
+	Outcome         string      `json:"outcome"`
+	FailureReason   FailureCode `json:"failure_reason,omitempty"`
+	Error           string      `json:"error,omitempty"`
+	StartedAt       time.Time   `json:"started_at"`
+	DurationSeconds float64     `json:"duration_seconds"`
+}
+
+// reportPhase is a single phase of the edit, like an upload or the commit.
+type reportPhase struct {
+	Name            string             `json:"name"`
+	Attempt         int                `json:"attempt"`
+	Outcome         string             `json:"outcome"`
+	Error           string             `json:"error,omitempty"`
+	StartedAt       time.Time          `json:"started_at"`
+	DurationSeconds float64            `json:"duration_seconds"`
+	Artifacts       []UploadedArtifact `json:"artifacts,omitempty"`
+}
+
+// UploadedArtifact is an app uploaded to the edit.
+type UploadedArtifact struct {
+	Path        string `json:"path"`
+	VersionCode int64  `json:"version_code"`
+	SHA256      string `json:"sha256"`
+	SizeBytes   int64  `json:"size_bytes"`
+	// DurationSeconds includes the upload of the expansion and mapping files of the app.
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+func newDeploymentReport(configs Configs, dryRun bool) *deploymentReport {
+	return &deploymentReport{
+		PackageName: configs.PackageName,
+		Track:       configs.Track,
+		DryRun:      dryRun,
+		Outcome:     outcomeRunning,
+		StartedAt:   time.Now(),
+	}
+}
+
+// startAttempt starts a new run of the edit flow.
+func (r *deploymentReport) startAttempt() {
+	r.Attempts = append(r.Attempts, &editAttempt{Number: len(r.Attempts) + 1, Outcome: outcomeRunning, StartedAt: time.Now()})
+}
+
+// endAttempt ends the current phase and run of the edit flow with the outcome of the given error.
+func (r *deploymentReport) endAttempt(err error) {
+	r.endPhase(err)
+	if len(r.Attempts) == 0 {
+		return
+	}
+	current := r.Attempts[len(r.Attempts)-1]
+	current.DurationSeconds = secondsSince(current.StartedAt)
+	if err != nil {
+		current.Outcome = outcomeFailed
+		current.Error = err.Error()
+		current.FailureReason = ClassifyError(err).Code
+		return
+	}
+	current.Outcome = outcomeSucceeded
+}
+
+// setEditID records the edit of the current attempt.
+func (r *deploymentReport) setEditID(editID string) {
+	r.EditID = editID
+	if len(r.Attempts) > 0 {
+		r.Attempts[len(r.Attempts)-1].EditID = editID
+	}
+}
+
+// startPhase finishes the running phase, if any, and starts the given one.
+func (r *deploymentReport) startPhase(name string) {
+	r.endPhase(nil)
+	r.Phases = append(r.Phases, &reportPhase{Name: name, Attempt: len(r.Attempts), Outcome: outcomeRunning, StartedAt: time.Now()})
+}
+
+// phase returns the name of the current phase.
+func (r *deploymentReport) phase() string {
+	if len(r.Phases) == 0 {
+		return ""
+	}
+	return r.Phases[len(r.Phases)-1].Name
+}
+
+// warn records an error of the current phase which does not fail the deployment.
+func (r *deploymentReport) warn(err error) {
+	if len(r.Phases) == 0 {
+		return
+	}
+	r.Phases[len(r.Phases)-1].Error = err.Error()
+}
+
+// addArtifacts records the apps uploaded in the current phase.
+func (r *deploymentReport) addArtifacts(artifacts []UploadedArtifact) {
+	if len(r.Phases) == 0 {
+		return
+	}
+	current := r.Phases[len(r.Phases)-1]
+	current.Artifacts = append(current.Artifacts, artifacts...)
+}
+
+// finish ends the current phase and the report with the outcome of the given error.
+func (r *deploymentReport) finish(err error) {
+	r.endPhase(err)
+	r.DurationSeconds = secondsSince(r.StartedAt)
+	if err != nil {
+		r.Outcome = outcomeFailed
+		r.Error = err.Error()
+		r.FailureReason = ClassifyError(err).Code
+		return
+	}
+	r.Outcome = outcomeSucceeded
+}
+
+func (r *deploymentReport) endPhase(err error) {
+	if len(r.Phases) == 0 {
+		return
+	}
+	current := r.Phases[len(r.Phases)-1]
+	if current.Outcome != outcomeRunning {
+		return
+	}
+	current.DurationSeconds = secondsSince(current.StartedAt)
+	switch {
+	case err != nil:
+		current.Outcome = outcomeFailed
+		current.Error = err.Error()
+	case current.Error != "":
+		current.Outcome = outcomeWarning
+	default:
+		current.Outcome = outcomeSucceeded
+	}
+}
+
+// writeDeploymentReport writes the report into the deploy directory in the configured formats. Returns the paths of
+// the reports as outputs.
+func (p *Publisher) writeDeploymentReport(configs Configs, report *deploymentReport) (Outputs, error) {
+	if configs.DeploymentReport == "" || configs.DeploymentReport == deploymentReportNone {
+		return nil, nil
+	}
+	if configs.DeployDir == "" {
+		p.logger.Warnf("Deploy directory is not set, skipping the deployment report")
+		return nil, nil
+	}
+
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize deployment report, error: %w", err)
+	}
+	pth := filepath.Join(configs.DeployDir, deploymentReportFileName)
+	if err := p.writeReportFile(pth, content); err != nil {
+		return nil, err
+	}
+	outputs := Outputs{deploymentReportPathKey: pth}
+
+	if configs.DeploymentReport == deploymentReportJSONAndJUnit {
+		content, err := report.junit()
+		if err != nil {
+			return outputs, fmt.Errorf("failed to serialize JUnit deployment report, error: %w", err)
+		}
+		pth := filepath.Join(configs.DeployDir, deploymentReportJUnitFileName)
+		if err := p.writeReportFile(pth, content); err != nil {
+			return outputs, err
+		}
+		outputs[deploymentReportJUnitPathKey] = pth
+	}
+	return outputs, nil
+}
+
+func (p *Publisher) writeReportFile(pth string, content []byte) error {
+	if err := os.WriteFile(pth, content, 0600); err != nil {
+		return fmt.Errorf("failed to write deployment report to %s, error: %w", pth, err)
+	}
+	p.logger.Printf(" deployment report written to: %s", pth)
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junit returns the report as a JUnit XML document, with a test case per phase.
+func (r *deploymentReport) junit() ([]byte, error) {
+	suite := junitTestSuite{
+		Name:      fmt.Sprintf("Google Play deploy of %s to %s", r.PackageName, r.Track),
+		Tests:     len(r.Phases),
+		Time:      formatSeconds(r.DurationSeconds),
+		Timestamp: r.StartedAt.UTC().Format(time.RFC3339),
+	}
+	for _, phase := range r.Phases {
+		name := phase.Name
+		if len(r.Attempts) > 1 {
+			name = fmt.Sprintf("%s (attempt %d)", phase.Name, phase.Attempt)
+		}
+		testCase := junitTestCase{Name: name, ClassName: r.PackageName, Time: formatSeconds(phase.DurationSeconds)}
+		switch phase.Outcome {
+		case outcomeFailed:
+			suite.Failures++
+			testCase.Failure = &junitFailure{Message: phase.Error, Text: phase.Error}
+		case outcomeWarning:
+			testCase.SystemOut = phase.Error
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	content, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), content...), nil
+}
+
+func secondsSince(t time.Time) float64 {
+	return math.Round(time.Since(t).Seconds()*1000) / 1000
+}
+
+func formatSeconds(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
@@ -0,0 +1,98 @@
+package googleplay
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_deploymentReport_phases(t *testing.T) {
+	report := newDeploymentReport(Configs{PackageName: "io.bitrise.sample", Track: "beta"}, false)
+	report.startPhase("Create new edit")
+	report.startPhase("Report country availability")
+	report.warn(errors.New("country availability not found"))
+	report.startPhase("Commit edit")
+	report.finish(errors.New("Failed to commit edit"))
+
+	assert.Equal(t, "Commit edit", report.phase())
+	assert.Equal(t, outcomeFailed, report.Outcome)
+	assert.Equal(t, "Failed to commit edit", report.Error)
+	assert.Equal(t, FailureUnknown, report.FailureReason)
+
+	var outcomes []string
+	for _, phase := range report.Phases {
+		outcomes = append(outcomes, phase.Outcome)
+	}
+	assert.Equal(t, []string{outcomeSucceeded, outcomeWarning, outcomeFailed}, outcomes)
+	assert.Equal(t, "country availability not found", report.Phases[1].Error)
+}
+
+func Test_deploymentReport_junit(t *testing.T) {
+	report := newDeploymentReport(Configs{PackageName: "io.bitrise.sample", Track: "beta"}, false)
+	report.startPhase("Create new edit")
+	report.startPhase("Commit edit")
+	report.finish(errors.New("Failed to commit edit"))
+
+	content, err := report.junit()
+	require.NoError(t, err)
+
+	var suites junitTestSuites
+	require.NoError(t, xml.Unmarshal(content, &suites))
+	require.Len(t, suites.Suites, 1)
+	suite := suites.Suites[0]
+	assert.Equal(t, 2, suite.Tests)
+	assert.Equal(t, 1, suite.Failures)
+	require.Len(t, suite.TestCases, 2)
+	assert.Nil(t, suite.TestCases[0].Failure)
+	require.NotNil(t, suite.TestCases[1].Failure)
+	assert.Equal(t, "Failed to commit edit", suite.TestCases[1].Failure.Message)
+}
+
+func TestPublisher_executeEdit_writesDeploymentReport(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	deployDir := t.TempDir()
+	configs := Configs{
+		PackageName:      packageName,
+		AppPath:          writeBundles(t, "1"),
+		Track:            "beta",
+		DeployDir:        deployDir,
+		DeploymentReport: deploymentReportJSONAndJUnit,
+		Logger:           log.NewLogger(),
+	}
+	_, publisher, service := newEmulatedPublisher(t, configs)
+
+	outputs := Outputs{}
+	require.NoError(t, publisher.executeEdit(context.Background(), service, configs, outputs, false, false))
+
+	pth := filepath.Join(deployDir, deploymentReportFileName)
+	assert.Equal(t, pth, outputs[deploymentReportPathKey])
+	content, err := os.ReadFile(pth)
+	require.NoError(t, err)
+	var report deploymentReport
+	require.NoError(t, json.Unmarshal(content, &report))
+
+	assert.Equal(t, outcomeSucceeded, report.Outcome)
+	assert.NotEmpty(t, report.EditID)
+	var phases []string
+	for _, phase := range report.Phases {
+		phases = append(phases, phase.Name)
+		assert.NotEqual(t, outcomeFailed, phase.Outcome, phase.Name)
+	}
+	assert.Equal(t, []string{"Create new edit", "List tracks", "Upload apks or app bundles", "Update track", "Report country availability", "Commit edit"}, phases)
+
+	upload := report.Phases[2]
+	require.Len(t, upload.Artifacts, 1)
+	assert.Equal(t, int64(1), upload.Artifacts[0].VersionCode)
+	assert.Equal(t, int64(len("versionCode=1")), upload.Artifacts[0].SizeBytes)
+	assert.NotEmpty(t, upload.Artifacts[0].SHA256)
+
+	assert.FileExists(t, outputs[deploymentReportJUnitPathKey])
+}
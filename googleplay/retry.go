@@ -0,0 +1,253 @@
+package googleplay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/go-utils/v2/retryhttp"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// callClass groups the requests retried with the same settings.
+type callClass string
+
+const (
+	callClassAuth     callClass = "auth"
+	callClassMetadata callClass = "metadata"
+	callClassUpload   callClass = "upload"
+)
+
+// retrySettings configures the retries of a call class.
+type retrySettings struct {
+	maxRetries int
+	// Exponential backoff bounds of server and network errors.
+	waitMin time.Duration
+	waitMax time.Duration
+	// Exponential backoff start of quota errors without a Retry-After header, capped at quotaWaitMax.
+	quotaWaitMin time.Duration
+	// Requests are not retried once this much time passed since the first attempt.
+	maxElapsed time.Duration
+}
+
+// The Google Play Developer API quotas are enforced per minute, so waiting longer than that is never needed.
+const quotaWaitMax = time.Minute
+
+var defaultRetrySettings = map[callClass]retrySettings{
+	callClassAuth:     {maxRetries: 3, waitMin: time.Second, waitMax: 10 * time.Second, quotaWaitMin: 5 * time.Second, maxElapsed: time.Minute},
+	callClassMetadata: {maxRetries: 8, waitMin: 2 * time.Second, waitMax: 30 * time.Second, quotaWaitMin: 15 * time.Second, maxElapsed: 5 * time.Minute},
+	callClassUpload:   {maxRetries: 5, waitMin: 5 * time.Second, waitMax: time.Minute, quotaWaitMin: 15 * time.Second, maxElapsed: 10 * time.Minute},
+}
+
+// quotaErrorReasons are the reasons of the retryable quota errors. Daily limits (dailyLimitExceeded) are not retried,
+// as they do not reset during the build.
+var quotaErrorReasons = map[string]bool{
+	"rateLimitExceeded":     true,
+	"userRateLimitExceeded": true,
+	"quotaExceeded":         true,
+	"RESOURCE_EXHAUSTED":    true,
+}
+
+type retryStartKey struct{}
+
+// retryPolicy decides if and when the requests of the API client are retried.
+type retryPolicy struct {
+	logger   log.Logger
+	settings map[callClass]retrySettings
+	// Set once the token endpoint rejected the credentials: retrying unauthorized API calls is pointless afterwards.
+	tokenRefreshFailed atomic.Bool
+}
+
+// retryTransport retries the requests according to the settings of their call class.
+type retryTransport struct {
+	roundTrippers map[callClass]http.RoundTripper
+	// apiBasePaths are the path prefixes of the configured API URLs, see Configs.apiBasePaths.
+	apiBasePaths []string
+}
+
+// newRetryTransport creates a transport retrying the requests sent through the given base transport, with a separate
+// retry client per call class. The uploads are recognized relative to the given API base paths.
+func (p *Publisher) newRetryTransport(base http.RoundTripper, settings map[callClass]retrySettings, apiBasePaths []string) http.RoundTripper {
+	policy := &retryPolicy{logger: p.logger, settings: settings}
+
+	roundTrippers := map[callClass]http.RoundTripper{}
+	for class, classSettings := range settings {
+		retryClient := retryhttp.NewClient(p.logger)
+		retryClient.HTTPClient.Transport = base
+		retryClient.RetryWaitMin = classSettings.waitMin
+		retryClient.RetryWaitMax = classSettings.waitMax
+		retryClient.RetryMax = classSettings.maxRetries
+		retryClient.CheckRetry = policy.checkRetry(class)
+		retryClient.Backoff = policy.backoff(class)
+		roundTrippers[class] = &retryablehttp.RoundTripper{Client: retryClient}
+	}
+	return retryTransport{roundTrippers: roundTrippers, apiBasePaths: apiBasePaths}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := context.WithValue(req.Context(), retryStartKey{}, time.Now())
+	return t.roundTrippers[classifyRequest(req, t.apiBasePaths)].RoundTrip(req.WithContext(ctx))
+}
+
+// classifyRequest returns the call class of the request: media uploads, other API calls or token requests.
+func classifyRequest(req *http.Request, apiBasePaths []string) callClass {
+	switch {
+	case isUploadRequest(req.URL, apiBasePaths):
+		return callClassUpload
+	case strings.Contains(req.URL.Path, "/androidpublisher/"):
+		return callClassMetadata
+	default:
+		return callClassAuth
+	}
+}
+
+func (r *retryPolicy) checkRetry(class callClass) retryablehttp.CheckRetry {
+	settings := r.settings[class]
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		if started, ok := ctx.Value(retryStartKey{}).(time.Time); ok && time.Since(started) >= settings.maxElapsed {
+			r.logger.Warnf("Giving up retrying %s request after %s", class, settings.maxElapsed)
+			return false, nil
+		}
+
+		if resp != nil {
+			switch {
+			case class == callClassAuth && resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests:
+				r.tokenRefreshFailed.Store(true)
+				return false, nil
+			case resp.StatusCode == http.StatusUnauthorized:
+				if r.tokenRefreshFailed.Load() {
+					return false, nil
+				}
+				r.logger.Debugf("Received HTTP 401 (Unauthorized), retrying request...")
+				return true, nil
+			case resp.StatusCode == http.StatusForbidden:
+				if reason := errorReason(resp); quotaErrorReasons[reason] {
+					return true, nil
+				}
+			}
+		}
+
+		shouldRetry, err := retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+		if shouldRetry && resp != nil {
+			r.logger.Debugf("Retry network error: %d", resp.StatusCode)
+		}
+
+		return shouldRetry, err
+	}
+}
+
+// backoff returns the wait before the next attempt: the Retry-After of the response if set, otherwise a jittered
+// exponential backoff, which starts higher for quota errors. The wait never exceeds the remaining retry time.
+func (r *retryPolicy) backoff(class callClass) retryablehttp.Backoff {
+	settings := r.settings[class]
+	return func(_, _ time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		if resp == nil {
+			return jitteredBackoff(settings.waitMin, settings.waitMax, attemptNum)
+		}
+
+		quotaError := isQuotaError(resp)
+		wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			if quotaError {
+				wait = jitteredBackoff(settings.quotaWaitMin, quotaWaitMax, attemptNum)
+			} else {
+				wait = jitteredBackoff(settings.waitMin, settings.waitMax, attemptNum)
+			}
+		}
+
+		if resp.Request != nil {
+			if started, ok := resp.Request.Context().Value(retryStartKey{}).(time.Time); ok {
+				if remaining := settings.maxElapsed - time.Since(started); wait > remaining {
+					wait = remaining
+				}
+			}
+		}
+		if wait < 0 {
+			wait = 0
+		}
+
+		if quotaError {
+			r.logger.Warnf("Google Play API quota exceeded, retrying in %s", wait.Round(time.Second))
+		}
+		return wait
+	}
+}
+
+// jitteredBackoff returns a random wait between the half and the whole of the exponential backoff of the attempt.
+func jitteredBackoff(waitMin, waitMax time.Duration, attemptNum int) time.Duration {
+	wait := time.Duration(math.Min(float64(waitMin)*math.Pow(2, float64(attemptNum)), float64(waitMax)))
+	if wait <= 1 {
+		return wait
+	}
+	half := wait / 2
+	return half + time.Duration(rand.Int63n(int64(wait-half)))
+}
+
+// parseRetryAfter parses the Retry-After header, given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func isQuotaError(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests || (resp.StatusCode == http.StatusForbidden && quotaErrorReasons[errorReason(resp)])
+}
+
+// errorReason returns the reason of a Google API error response (for example rateLimitExceeded), or its status if it
+// has no reason. The response body is kept readable.
+func errorReason(resp *http.Response) string {
+	if resp.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var errorResponse struct {
+		Error struct {
+			Status string `json:"status"`
+			Errors []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errorResponse); err != nil {
+		return ""
+	}
+	for _, item := range errorResponse.Error.Errors {
+		if item.Reason != "" {
+			return item.Reason
+		}
+	}
+	return errorResponse.Error.Status
+}
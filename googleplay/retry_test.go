@@ -0,0 +1,210 @@
+package googleplay
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRetrySettings(maxElapsed time.Duration) map[callClass]retrySettings {
+	settings := retrySettings{maxRetries: 3, waitMin: time.Millisecond, waitMax: time.Millisecond, quotaWaitMin: time.Millisecond, maxElapsed: maxElapsed}
+	return map[callClass]retrySettings{callClassAuth: settings, callClassMetadata: settings, callClassUpload: settings}
+}
+
+func googleErrorResponse(w http.ResponseWriter, code int, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = fmt.Fprintf(w, `{"error": {"code": %d, "message": "error", "errors": [{"reason": %q}]}}`, code, reason)
+}
+
+func Test_retryTransport(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		maxElapsed   time.Duration
+		handler      func(w http.ResponseWriter, attempt int32)
+		wantStatus   int
+		wantAttempts int32
+	}{
+		{
+			name: "rate limited request retried after Retry-After",
+			path: "/androidpublisher/v3/applications/io.bitrise/edits",
+			handler: func(w http.ResponseWriter, attempt int32) {
+				if attempt < 3 {
+					w.Header().Set("Retry-After", "0")
+					googleErrorResponse(w, http.StatusTooManyRequests, "rateLimitExceeded")
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			},
+			wantStatus:   http.StatusOK,
+			wantAttempts: 3,
+		},
+		{
+			name: "quota error retried",
+			path: "/upload/androidpublisher/v3/applications/io.bitrise/edits/1/bundles",
+			handler: func(w http.ResponseWriter, attempt int32) {
+				if attempt < 2 {
+					googleErrorResponse(w, http.StatusForbidden, "userRateLimitExceeded")
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			},
+			wantStatus:   http.StatusOK,
+			wantAttempts: 2,
+		},
+		{
+			name: "daily limit not retried",
+			path: "/androidpublisher/v3/applications/io.bitrise/edits",
+			handler: func(w http.ResponseWriter, _ int32) {
+				googleErrorResponse(w, http.StatusForbidden, "dailyLimitExceeded")
+			},
+			wantStatus:   http.StatusForbidden,
+			wantAttempts: 1,
+		},
+		{
+			name: "retries exhausted",
+			path: "/androidpublisher/v3/applications/io.bitrise/edits",
+			handler: func(w http.ResponseWriter, _ int32) {
+				googleErrorResponse(w, http.StatusInternalServerError, "backendError")
+			},
+			wantStatus:   http.StatusInternalServerError,
+			wantAttempts: 4,
+		},
+		{
+			name:       "total retry time capped",
+			path:       "/androidpublisher/v3/applications/io.bitrise/edits",
+			maxElapsed: time.Nanosecond,
+			handler: func(w http.ResponseWriter, _ int32) {
+				googleErrorResponse(w, http.StatusTooManyRequests, "rateLimitExceeded")
+			},
+			wantStatus:   http.StatusTooManyRequests,
+			wantAttempts: 1,
+		},
+		{
+			name: "rejected token request not retried",
+			path: "/token",
+			handler: func(w http.ResponseWriter, _ int32) {
+				w.WriteHeader(http.StatusBadRequest)
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(w, atomic.AddInt32(&attempts, 1))
+			}))
+			defer server.Close()
+
+			maxElapsed := tt.maxElapsed
+			if maxElapsed == 0 {
+				maxElapsed = time.Minute
+			}
+			client := &http.Client{Transport: NewPublisher(log.NewLogger()).newRetryTransport(http.DefaultTransport, testRetrySettings(maxElapsed), nil)}
+
+			resp, err := client.Post(server.URL+tt.path, "application/json", strings.NewReader("{}"))
+			require.NoError(t, err)
+			defer func() {
+				require.NoError(t, resp.Body.Close())
+			}()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantAttempts, atomic.LoadInt32(&attempts))
+		})
+	}
+}
+
+func Test_retryTransport_unauthorizedAfterTokenRefreshFailed(t *testing.T) {
+	var apiAttempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		atomic.AddInt32(&apiAttempts, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewPublisher(log.NewLogger()).newRetryTransport(http.DefaultTransport, testRetrySettings(time.Minute), nil)}
+	get := func(path string) {
+		resp, err := client.Get(server.URL + path)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	get("/androidpublisher/v3/applications/io.bitrise/edits/1")
+	assert.Equal(t, int32(4), atomic.LoadInt32(&apiAttempts), "unauthorized API calls are retried")
+
+	get("/token")
+	get("/androidpublisher/v3/applications/io.bitrise/edits/1")
+	assert.Equal(t, int32(5), atomic.LoadInt32(&apiAttempts), "unauthorized API calls are not retried after a failed token refresh")
+}
+
+func Test_classifyRequest(t *testing.T) {
+	tests := []struct {
+		url  string
+		want callClass
+	}{
+		{url: "https://androidpublisher.googleapis.com/androidpublisher/v3/applications/io.bitrise/edits", want: callClassMetadata},
+		{url: "https://androidpublisher.googleapis.com/upload/androidpublisher/v3/applications/io.bitrise/edits/1/bundles", want: callClassUpload},
+		{url: "https://proxy.example.com/play/upload/androidpublisher/v3/applications/io.bitrise/edits/1/apks", want: callClassUpload},
+		{url: "https://proxy.example.com/play/androidpublisher/v3/applications/upload/edits", want: callClassMetadata},
+		{url: "https://oauth2.googleapis.com/token", want: callClassAuth},
+		{url: "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/sa@example.com:generateAccessToken", want: callClassAuth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, classifyRequest(req, []string{"/play/"}))
+		})
+	}
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "", want: 0, wantOK: false},
+		{value: "30", want: 30 * time.Second, wantOK: true},
+		{value: "-1", want: 0, wantOK: false},
+		{value: "Mon, 01 Jan 2024 12:01:00 GMT", want: time.Minute, wantOK: true},
+		{value: "Mon, 01 Jan 2024 11:00:00 GMT", want: 0, wantOK: true},
+		{value: "soon", want: 0, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_jitteredBackoff(t *testing.T) {
+	for attempt := 0; attempt < 10; attempt++ {
+		wait := jitteredBackoff(2*time.Second, 30*time.Second, attempt)
+		expected := time.Duration(float64(2*time.Second) * float64(int(1)<<attempt))
+		if expected > 30*time.Second {
+			expected = 30 * time.Second
+		}
+		assert.GreaterOrEqual(t, wait, expected/2)
+		assert.LessOrEqual(t, wait, expected)
+	}
+}
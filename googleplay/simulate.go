@@ -0,0 +1,19 @@
+package googleplay
+
+import (
+	"net/http"
+
+	"github.com/bitrise-steplib/steps-google-play-deploy/emulator"
+)
+
+// createSimulatedClient creates an HTTP client sending the requests to an in-memory Google Play Developer API emulator,
+// with an app of the configured package name. The app has the built-in tracks and the configured custom tracks only,
+// so that a misspelled track fails the simulation like it would fail on Google Play.
+func (p *Publisher) createSimulatedClient(configs Configs) *http.Client {
+	if configs.Logger == nil {
+		configs.Logger = p.logger
+	}
+	server := emulator.NewServer()
+	server.AddApp(configs.PackageName, emulator.WithTracks(configs.parseInputList(configs.SimulatedCustomTracks)...))
+	return server.Client()
+}
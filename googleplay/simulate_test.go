@@ -0,0 +1,66 @@
+package googleplay
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublisher_Deploy_simulate(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs Configs
+		wantErr string
+	}{
+		{
+			name:    "built-in track",
+			configs: Configs{AppPath: writeBundles(t, "2"), Track: "beta"},
+		},
+		{
+			name:    "misspelled track",
+			configs: Configs{AppPath: writeBundles(t, "2"), Track: "bta"},
+			wantErr: "Track not found: bta. Did you mean: beta?",
+		},
+		{
+			name:    "custom track",
+			configs: Configs{AppPath: writeBundles(t, "2"), Track: "qa-team", SimulatedCustomTracks: "qa-team|staff"},
+		},
+		{
+			name:    "unlisted custom track",
+			configs: Configs{AppPath: writeBundles(t, "2"), Track: "qa_team", SimulatedCustomTracks: "qa-team"},
+			wantErr: "Track not found: qa_team. Did you mean: qa-team?",
+		},
+		{
+			name:    "generated APKs download",
+			configs: Configs{AppPath: writeBundles(t, "2"), Track: "beta", GeneratedApksDownload: generatedApksDownloadUniversal, DeployDir: t.TempDir()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs := tt.configs
+			configs.PackageName = "io.bitrise.sample"
+			configs.Simulate = true
+			publisher := New(Options{})
+			service, err := publisher.NewService(context.Background(), configs)
+			require.NoError(t, err)
+
+			outputs, err := publisher.Deploy(context.Background(), service, configs)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.Equal(t, FailureTrackNotFound, ClassifyError(err).Code)
+				return
+			}
+			require.NoError(t, err)
+
+			if configs.GeneratedApksDownload != "" {
+				pth := filepath.Join(configs.DeployDir, "generated-apks", "2", "universal.apk")
+				assert.Equal(t, pth, outputs[universalApkPathListKey])
+				assert.FileExists(t, pth)
+			}
+		})
+	}
+}
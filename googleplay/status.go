@@ -0,0 +1,177 @@
+package googleplay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+const (
+	tracksStatusFileName = "google-play-tracks.json"
+	tracksStatusKey      = "GOOGLE_PLAY_TRACKS_STATUS"
+	tracksStatusPathKey  = "GOOGLE_PLAY_TRACKS_STATUS_PATH"
+)
+
+// trackStatus is the state of a track with its releases, as printed and exported by the status mode.
+type trackStatus struct {
+	Track    string          `json:"track"`
+	Releases []releaseStatus `json:"releases"`
+}
+
+type releaseStatus struct {
+	Name         string  `json:"name,omitempty"`
+	Status       string  `json:"status"`
+	UserFraction float64 `json:"user_fraction,omitempty"`
+	VersionCodes []int64 `json:"version_codes"`
+	// Countries is empty if the release is not targeted to countries.
+	Countries          []string          `json:"countries,omitempty"`
+	IncludeRestOfWorld bool              `json:"include_rest_of_world,omitempty"`
+	ReleaseNotes       map[string]string `json:"release_notes,omitempty"`
+}
+
+func newTrackStatuses(tracks []*androidpublisher.Track) []trackStatus {
+	statuses := []trackStatus{}
+	for _, track := range tracks {
+		status := trackStatus{Track: track.Track, Releases: []releaseStatus{}}
+		for _, release := range track.Releases {
+			r := releaseStatus{
+				Name:         release.Name,
+				Status:       release.Status,
+				UserFraction: release.UserFraction,
+				VersionCodes: release.VersionCodes,
+			}
+			if release.CountryTargeting != nil {
+				r.Countries = release.CountryTargeting.Countries
+				r.IncludeRestOfWorld = release.CountryTargeting.IncludeRestOfWorld
+			}
+			if len(release.ReleaseNotes) > 0 {
+				r.ReleaseNotes = map[string]string{}
+				for _, notes := range release.ReleaseNotes {
+					r.ReleaseNotes[notes.Language] = notes.Text
+				}
+			}
+			status.Releases = append(status.Releases, r)
+		}
+		statuses = append(statuses, status)
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].Track < statuses[j].Track
+	})
+	return statuses
+}
+
+// WriteTracksTable writes the tracks with their releases as a table.
+func WriteTracksTable(w io.Writer, tracks []*androidpublisher.Track) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TRACK\tRELEASE\tSTATUS\tUSER FRACTION\tVERSION CODES\tCOUNTRIES\tRELEASE NOTES")
+	for _, track := range newTrackStatuses(tracks) {
+		if len(track.Releases) == 0 {
+			fmt.Fprintf(table, "%s\t-\t-\t-\t-\t-\t-\n", track.Track)
+			continue
+		}
+		for _, release := range track.Releases {
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				track.Track,
+				orDash(release.Name),
+				release.Status,
+				formatUserFraction(release.UserFraction),
+				orDash(formatVersionCodes(release.VersionCodes)),
+				formatCountries(release),
+				orDash(formatReleaseNotes(release.ReleaseNotes)),
+			)
+		}
+	}
+	return table.Flush()
+}
+
+// printStatus prints every track of the app with its releases, and writes them as JSON into the deploy directory if
+// set. Returns the tracks as JSON and the path of the file as outputs. The temporary edit the tracks are read with is
+// deleted.
+func (p *Publisher) printStatus(ctx context.Context, service *androidpublisher.Service, configs Configs) (Outputs, error) {
+	tracks, err := p.Tracks(ctx, service, configs)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println()
+	if err := WriteTracksTable(os.Stdout, tracks); err != nil {
+		return nil, fmt.Errorf("failed to print tracks, error: %w", err)
+	}
+	fmt.Println()
+
+	content, err := json.Marshal(newTrackStatuses(tracks))
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize tracks, error: %w", err)
+	}
+	outputs := Outputs{tracksStatusKey: string(content)}
+	if configs.DeployDir != "" {
+		pth := filepath.Join(configs.DeployDir, tracksStatusFileName)
+		if err := os.WriteFile(pth, content, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write tracks to %s, error: %w", pth, err)
+		}
+		p.logger.Printf(" tracks written to: %s", pth)
+		outputs[tracksStatusPathKey] = pth
+	}
+	return outputs, nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func formatUserFraction(fraction float64) string {
+	if fraction == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%g", fraction)
+}
+
+func formatVersionCodes(versionCodes []int64) string {
+	var codes []string
+	for _, code := range versionCodes {
+		codes = append(codes, fmt.Sprint(code))
+	}
+	return strings.Join(codes, ",")
+}
+
+func formatCountries(release releaseStatus) string {
+	if len(release.Countries) == 0 {
+		return "all"
+	}
+	countries := strings.Join(release.Countries, ",")
+	if release.IncludeRestOfWorld {
+		countries += " + rest of world"
+	}
+	return countries
+}
+
+// formatReleaseNotes returns the first line of the release notes by language, truncated to fit into a table.
+func formatReleaseNotes(notes map[string]string) string {
+	const maxLength = 30
+	var languages []string
+	for language := range notes {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	var formatted []string
+	for _, language := range languages {
+		text := []rune(strings.TrimSpace(strings.SplitN(strings.TrimSpace(notes[language]), "\n", 2)[0]))
+		if len(text) > maxLength {
+			text = append(text[:maxLength], '…')
+		}
+		formatted = append(formatted, fmt.Sprintf("%s: %s", language, string(text)))
+	}
+	return strings.Join(formatted, "; ")
+}
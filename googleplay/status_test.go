@@ -0,0 +1,100 @@
+package googleplay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-google-play-deploy/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+func TestWriteTracksTable(t *testing.T) {
+	tracks := []*androidpublisher.Track{
+		{Track: "production", Releases: []*androidpublisher.TrackRelease{
+			{Name: "1.0", Status: releaseStatusCompleted, VersionCodes: []int64{1}},
+			{
+				Name:             "2.0",
+				Status:           releaseStatusInProgress,
+				UserFraction:     0.1,
+				VersionCodes:     []int64{2, 3},
+				CountryTargeting: &androidpublisher.CountryTargeting{Countries: []string{"DE", "FR"}, IncludeRestOfWorld: true},
+				ReleaseNotes: []*androidpublisher.LocalizedText{
+					{Language: "en-US", Text: "Bug fixes and a much faster start of the app\nMore details"},
+					{Language: "de-DE", Text: "Fehlerbehebungen"},
+				},
+			},
+		}},
+		{Track: "beta"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteTracksTable(&buf, tracks))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, []string{"TRACK", "RELEASE", "STATUS", "USER", "FRACTION", "VERSION", "CODES", "COUNTRIES", "RELEASE", "NOTES"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"beta", "-", "-", "-", "-", "-", "-"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"production", "1.0", "completed", "-", "1", "all", "-"}, strings.Fields(lines[2]))
+	assert.Contains(t, lines[3], "inProgress")
+	assert.Contains(t, lines[3], "0.1")
+	assert.Contains(t, lines[3], "2,3")
+	assert.Contains(t, lines[3], "DE,FR + rest of world")
+	assert.Contains(t, lines[3], "de-DE: Fehlerbehebungen; en-US: Bug fixes and a much faster st…")
+}
+
+func TestPublisher_Deploy_status(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	server := emulator.NewServer()
+	server.AddApp(packageName, emulator.WithRelease("production", androidpublisher.TrackRelease{
+		Name:         "1.0",
+		Status:       releaseStatusCompleted,
+		VersionCodes: []int64{1},
+		ReleaseNotes: []*androidpublisher.LocalizedText{{Language: "en-US", Text: "Bug fixes"}},
+	}))
+
+	deployDir := t.TempDir()
+	configs := Configs{PackageName: packageName, Mode: ModeStatus, DeployDir: deployDir}
+	exported := Outputs{}
+	publisher := New(Options{HTTPClient: server.Client(), Outputs: func(key, value string) error {
+		exported[key] = value
+		return nil
+	}})
+	service, err := publisher.NewService(context.Background(), configs)
+	require.NoError(t, err)
+
+	outputs, err := publisher.Deploy(context.Background(), service, configs)
+	require.NoError(t, err)
+	assert.Equal(t, 0, server.OpenEdits(packageName))
+	assert.Equal(t, 0, server.Commits(packageName))
+
+	pth := filepath.Join(deployDir, tracksStatusFileName)
+	content, err := os.ReadFile(pth)
+	require.NoError(t, err)
+	assert.Equal(t, Outputs{tracksStatusKey: string(content), tracksStatusPathKey: pth}, outputs)
+	assert.Equal(t, outputs, exported)
+	var statuses []trackStatus
+	require.NoError(t, json.Unmarshal(content, &statuses))
+	require.Len(t, statuses, len(emulator.BuiltInTracks))
+	production := statuses[len(statuses)-1]
+	assert.Equal(t, trackStatus{Track: "production", Releases: []releaseStatus{
+		{Name: "1.0", Status: releaseStatusCompleted, VersionCodes: []int64{1}, ReleaseNotes: map[string]string{"en-US": "Bug fixes"}},
+	}}, production)
+}
+
+func TestPublisher_listTracks_failure(t *testing.T) {
+	server := emulator.NewServer()
+	publisher := New(Options{HTTPClient: server.Client()})
+	configs := Configs{PackageName: "io.bitrise.unknown"}
+	service, err := publisher.NewService(context.Background(), configs)
+	require.NoError(t, err)
+
+	assert.NotPanics(t, func() {
+		publisher.listTracks(context.Background(), configs, service, &androidpublisher.AppEdit{Id: "1"})
+	})
+}
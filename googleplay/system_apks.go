@@ -0,0 +1,154 @@
+package googleplay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/api/androidpublisher/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const systemApkPathKey = "GOOGLE_PLAY_SYSTEM_APK_PATH"
+
+// System APKs are generated asynchronously after the variant is created.
+const systemApkDownloadAttempts = 30
+
+// systemApkDownloadWaitInterval is the wait before downloading a system APK which was not generated yet again.
+var systemApkDownloadWaitInterval = 20 * time.Second
+
+// generateSystemApk creates (or reuses) a system APK variant of the configured bundle version code for the device spec
+// read from the configured JSON file, then downloads the generated APK into the deploy directory. Returns its path as
+// output.
+func (p *Publisher) generateSystemApk(ctx context.Context, configs Configs, service *androidpublisher.Service) (Outputs, error) {
+	deviceSpec, err := readDeviceSpec(configs.SystemApkDeviceSpecPath)
+	if err != nil {
+		return nil, err
+	}
+	p.logger.Printf(" device spec: ABIs %v, screen density %d, locales %v", deviceSpec.SupportedAbis, deviceSpec.ScreenDensity, deviceSpec.SupportedLocales)
+
+	variantsService := androidpublisher.NewSystemapksVariantsService(service)
+	versionCode := int64(configs.SystemApkVersionCode)
+
+	listCtx, cancelList := callContext(ctx)
+	defer cancelList()
+	variants, err := variantsService.List(configs.PackageName, versionCode).Context(listCtx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list system APK variants of version code %d, error: %w", versionCode, err)
+	}
+
+	variant := findVariant(variants.Variants, deviceSpec)
+	if variant != nil {
+		p.logger.Printf(" reusing existing variant: %d", variant.VariantId)
+	} else {
+		createCtx, cancelCreate := callContext(ctx)
+		defer cancelCreate()
+		variant, err = variantsService.Create(configs.PackageName, versionCode, &androidpublisher.Variant{DeviceSpec: deviceSpec}).Context(createCtx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create system APK variant of version code %d, error: %w", versionCode, err)
+		}
+		p.logger.Printf(" created variant: %d", variant.VariantId)
+	}
+
+	pth := filepath.Join(configs.DeployDir, "system-apks", fmt.Sprintf("%d-%d.apk", versionCode, variant.VariantId))
+	if err := p.downloadSystemApk(ctx, variantsService, configs.PackageName, versionCode, variant.VariantId, pth); err != nil {
+		return nil, err
+	}
+
+	return Outputs{systemApkPathKey: pth}, nil
+}
+
+// downloadSystemApk downloads the APK of the given variant, polling until it is generated. Any other error than the
+// APK not being ready yet fails the download right away.
+func (p *Publisher) downloadSystemApk(ctx context.Context, variantsService *androidpublisher.SystemapksVariantsService, packageName string, versionCode int64, variantID int64, pth string) error {
+	for attempt := 1; ; attempt++ {
+		callCtx, cancel := callContext(ctx)
+		resp, err := variantsService.Download(packageName, versionCode, variantID).Context(callCtx).Download()
+		if err == nil {
+			err := p.writeResponseToFile(resp, pth)
+			cancel()
+			if err != nil {
+				return err
+			}
+			p.logger.Printf(" downloaded: %s", pth)
+			return nil
+		}
+		cancel()
+		if attempt == systemApkDownloadAttempts || ctx.Err() != nil || !isSystemApkNotReady(err) {
+			return fmt.Errorf("failed to download system APK of variant %d, error: %w", variantID, err)
+		}
+
+		p.logger.Printf(" system APK is not ready yet (%d/%d), retrying in %s", attempt, systemApkDownloadAttempts, systemApkDownloadWaitInterval)
+		p.logger.Debugf("Download error: %s", err)
+		if err := sleepContext(ctx, systemApkDownloadWaitInterval); err != nil {
+			return fmt.Errorf("failed to wait for system APK of variant %d, error: %w", variantID, err)
+		}
+	}
+}
+
+// isSystemApkNotReady returns true if the download of a system APK failed because Google Play is still generating it.
+func isSystemApkNotReady(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.Code == http.StatusConflict || apiErr.Code == http.StatusPreconditionFailed {
+		return true
+	}
+	for _, item := range apiErr.Errors {
+		if item.Reason == "failedPrecondition" {
+			return true
+		}
+	}
+	return false
+}
+
+// readDeviceSpec reads a device spec from the given JSON file.
+// Example: {"supportedAbis": ["arm64-v8a"], "screenDensity": 480, "supportedLocales": ["en-US"]}
+func readDeviceSpec(pth string) (*androidpublisher.DeviceSpec, error) {
+	content, err := os.ReadFile(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device spec (%s), error: %w", pth, err)
+	}
+
+	// Unknown fields are rejected so that settings the API does not support (for example an SDK version) are not
+	// silently ignored.
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+
+	var deviceSpec androidpublisher.DeviceSpec
+	if err := decoder.Decode(&deviceSpec); err != nil {
+		return nil, fmt.Errorf("failed to parse device spec (%s), supported fields: supportedAbis, screenDensity, supportedLocales, error: %w", pth, err)
+	}
+
+	if len(deviceSpec.SupportedAbis) == 0 {
+		return nil, fmt.Errorf("device spec (%s) does not specify any supportedAbis", pth)
+	}
+	if deviceSpec.ScreenDensity <= 0 {
+		return nil, fmt.Errorf("device spec (%s) does not specify a valid screenDensity", pth)
+	}
+	return &deviceSpec, nil
+}
+
+// findVariant returns the variant with the same device spec as the given one, or nil if there is none.
+func findVariant(variants []*androidpublisher.Variant, deviceSpec *androidpublisher.DeviceSpec) *androidpublisher.Variant {
+	for _, variant := range variants {
+		if variant.DeviceSpec != nil && deviceSpecKey(variant.DeviceSpec) == deviceSpecKey(deviceSpec) {
+			return variant
+		}
+	}
+	return nil
+}
+
+func deviceSpecKey(deviceSpec *androidpublisher.DeviceSpec) string {
+	abis := sortedCopy(deviceSpec.SupportedAbis)
+	locales := sortedCopy(deviceSpec.SupportedLocales)
+	return fmt.Sprintf("%s/%d/%s", strings.Join(abis, ","), deviceSpec.ScreenDensity, strings.Join(locales, ","))
+}
@@ -0,0 +1,176 @@
+package googleplay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-google-play-deploy/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+func TestPublisher_Deploy_systemApks(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	deployDir := t.TempDir()
+	deviceSpecPath := filepath.Join(t.TempDir(), "device-spec.json")
+	require.NoError(t, os.WriteFile(deviceSpecPath, []byte(`{"supportedAbis": ["arm64-v8a"], "screenDensity": 480}`), 0600))
+	configs := Configs{
+		PackageName:             packageName,
+		Mode:                    ModeSystemApks,
+		SystemApkVersionCode:    5,
+		SystemApkDeviceSpecPath: deviceSpecPath,
+		DeployDir:               deployDir,
+		Logger:                  log.NewLogger(),
+	}
+	_, publisher, service := newEmulatedPublisher(t, configs, emulator.WithRelease("production", androidpublisher.TrackRelease{Status: releaseStatusCompleted, VersionCodes: []int64{5}}))
+
+	// The variant created by the first run is reused by the second one.
+	for run := 1; run <= 2; run++ {
+		outputs, err := publisher.Deploy(context.Background(), service, configs)
+		require.NoError(t, err)
+
+		pth := filepath.Join(deployDir, "system-apks", "5-1.apk")
+		assert.Equal(t, Outputs{systemApkPathKey: pth}, outputs)
+		content, err := os.ReadFile(pth)
+		require.NoError(t, err)
+		assert.Equal(t, emulator.SystemApk(5, 1), content)
+	}
+
+	configs.SystemApkVersionCode = 6
+	_, err := publisher.Deploy(context.Background(), service, configs)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "No app bundle found with version code 6")
+}
+
+func TestPublisher_Deploy_systemApkNotReady(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	wait := systemApkDownloadWaitInterval
+	systemApkDownloadWaitInterval = 0
+	t.Cleanup(func() { systemApkDownloadWaitInterval = wait })
+
+	tests := []struct {
+		name          string
+		failures      []int
+		wantErr       bool
+		wantDownloads int32
+	}{
+		{name: "downloaded once generated", failures: []int{http.StatusConflict, http.StatusConflict}, wantDownloads: 3},
+		{name: "permission error not retried", failures: []int{http.StatusForbidden}, wantErr: true, wantDownloads: 1},
+		{name: "missing variant not retried", failures: []int{http.StatusNotFound}, wantErr: true, wantDownloads: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := emulator.NewServer()
+			server.AddApp(packageName, emulator.WithRelease("production", androidpublisher.TrackRelease{Status: releaseStatusCompleted, VersionCodes: []int64{5}}))
+
+			var downloads int32
+			client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				if strings.HasSuffix(req.URL.Path, ":download") {
+					if download := atomic.AddInt32(&downloads, 1); int(download) <= len(tt.failures) {
+						recorder := httptest.NewRecorder()
+						googleErrorResponse(recorder, tt.failures[download-1], "error")
+						resp := recorder.Result()
+						resp.Request = req
+						return resp, nil
+					}
+				}
+				return server.RoundTrip(req)
+			})}
+
+			deviceSpecPath := filepath.Join(t.TempDir(), "device-spec.json")
+			require.NoError(t, os.WriteFile(deviceSpecPath, []byte(`{"supportedAbis": ["arm64-v8a"], "screenDensity": 480}`), 0600))
+			configs := Configs{
+				PackageName:             packageName,
+				Mode:                    ModeSystemApks,
+				SystemApkVersionCode:    5,
+				SystemApkDeviceSpecPath: deviceSpecPath,
+				DeployDir:               t.TempDir(),
+				Logger:                  log.NewLogger(),
+			}
+			publisher := New(Options{HTTPClient: client})
+			service, err := publisher.NewService(context.Background(), configs)
+			require.NoError(t, err)
+
+			_, err = publisher.Deploy(context.Background(), service, configs)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantDownloads, atomic.LoadInt32(&downloads))
+		})
+	}
+}
+
+func Test_isSystemApkNotReady(t *testing.T) {
+	assert.True(t, isSystemApkNotReady(apiErrorOf(http.StatusConflict, "The system APK is being generated.", "")))
+	assert.True(t, isSystemApkNotReady(apiErrorOf(http.StatusBadRequest, "The system APK is not ready.", "failedPrecondition")))
+	assert.False(t, isSystemApkNotReady(apiErrorOf(http.StatusNotFound, "Variant not found.", "notFound")))
+	assert.False(t, isSystemApkNotReady(apiErrorOf(http.StatusForbidden, "The caller does not have permission.", "forbidden")))
+	assert.False(t, isSystemApkNotReady(context.Canceled))
+}
+
+func Test_readDeviceSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *androidpublisher.DeviceSpec
+		wantErr bool
+	}{
+		{
+			name:    "valid spec",
+			content: `{"supportedAbis": ["arm64-v8a"], "screenDensity": 480, "supportedLocales": ["en-US"]}`,
+			want:    &androidpublisher.DeviceSpec{SupportedAbis: []string{"arm64-v8a"}, ScreenDensity: 480, SupportedLocales: []string{"en-US"}},
+		},
+		{
+			name:    "unsupported field",
+			content: `{"supportedAbis": ["arm64-v8a"], "screenDensity": 480, "sdkVersion": 33}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing ABIs",
+			content: `{"screenDensity": 480}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing screen density",
+			content: `{"supportedAbis": ["arm64-v8a"]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pth := filepath.Join(t.TempDir(), "device_spec.json")
+			require.NoError(t, os.WriteFile(pth, []byte(tt.content), 0600))
+
+			got, err := readDeviceSpec(pth)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_findVariant(t *testing.T) {
+	variants := []*androidpublisher.Variant{
+		{VariantId: 1, DeviceSpec: &androidpublisher.DeviceSpec{SupportedAbis: []string{"x86"}, ScreenDensity: 320}},
+		{VariantId: 2, DeviceSpec: &androidpublisher.DeviceSpec{SupportedAbis: []string{"armeabi-v7a", "arm64-v8a"}, ScreenDensity: 480}},
+	}
+
+	found := findVariant(variants, &androidpublisher.DeviceSpec{SupportedAbis: []string{"arm64-v8a", "armeabi-v7a"}, ScreenDensity: 480})
+	require.NotNil(t, found)
+	assert.Equal(t, int64(2), found.VariantId)
+
+	assert.Nil(t, findVariant(variants, &androidpublisher.DeviceSpec{SupportedAbis: []string{"x86"}, ScreenDensity: 480}))
+}
@@ -0,0 +1,62 @@
+package googleplay
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+const (
+	testersUpdateModeSet    = "set"
+	testersUpdateModeAppend = "append"
+)
+
+// updateTesters sets or appends the configured Google Groups as testers of the given track.
+func (p *Publisher) updateTesters(ctx context.Context, configs Configs, service *androidpublisher.Service, appEdit *androidpublisher.AppEdit) error {
+	groups := configs.parseInputList(configs.TestersGoogleGroups)
+	editsTestersService := androidpublisher.NewEditsTestersService(service)
+
+	if configs.TestersUpdateMode != testersUpdateModeSet {
+		callCtx, cancel := callContext(ctx)
+		defer cancel()
+		testers, err := editsTestersService.Get(configs.PackageName, appEdit.Id, configs.Track).Context(callCtx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to get testers of track %s, error: %w", configs.Track, err)
+		}
+		p.logger.Printf(" current tester groups: %v", testers.GoogleGroups)
+		groups = mergeGoogleGroups(testers.GoogleGroups, groups)
+	}
+
+	p.logger.Infof("%s track testers will be updated.", configs.Track)
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	testers, err := editsTestersService.Update(configs.PackageName, appEdit.Id, configs.Track, &androidpublisher.Testers{
+		GoogleGroups:    groups,
+		ForceSendFields: []string{"GoogleGroups"},
+	}).Context(callCtx).Do()
+	if err != nil {
+		return fmt.Errorf("update call failed, error: %w", err)
+	}
+
+	p.logger.Printf(" updated tester groups: %v", testers.GoogleGroups)
+	return nil
+}
+
+// mergeGoogleGroups appends the new groups to the existing ones, skipping the duplicates.
+func mergeGoogleGroups(existing, groups []string) []string {
+	merged := append([]string{}, existing...)
+	seen := map[string]bool{}
+	for _, group := range existing {
+		seen[group] = true
+	}
+
+	for _, group := range groups {
+		if seen[group] {
+			continue
+		}
+		seen[group] = true
+		merged = append(merged, group)
+	}
+	return merged
+}
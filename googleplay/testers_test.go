@@ -0,0 +1,86 @@
+package googleplay
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-google-play-deploy/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/androidpublisher/v3"
+)
+
+func TestPublisher_updateTesters(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	tests := []struct {
+		name       string
+		updateMode string
+		groups     string
+		want       []string
+	}{
+		{
+			name:       "append",
+			updateMode: testersUpdateModeAppend,
+			groups:     "qa@example.com|beta@example.com",
+			want:       []string{"qa@example.com", "team@example.com", "beta@example.com"},
+		},
+		{
+			name:       "set",
+			updateMode: testersUpdateModeSet,
+			groups:     "beta@example.com",
+			want:       []string{"beta@example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs := Configs{PackageName: packageName, Track: "beta", TestersGoogleGroups: tt.groups, TestersUpdateMode: tt.updateMode, Logger: log.NewLogger()}
+			server, publisher, service := newEmulatedPublisher(t, configs, emulator.WithTesters("beta", "qa@example.com", "team@example.com"))
+
+			err := publisher.inEdit(context.Background(), service, configs, true, func(ctx context.Context, editID string) error {
+				return publisher.updateTesters(ctx, configs, service, &androidpublisher.AppEdit{Id: editID})
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, server.Testers(packageName, "beta").GoogleGroups)
+			assert.Nil(t, server.Testers(packageName, "alpha"))
+		})
+	}
+}
+
+func Test_mergeGoogleGroups(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		groups   []string
+		want     []string
+	}{
+		{"no existing groups", nil, []string{"qa@example.com"}, []string{"qa@example.com"}},
+		{"new group appended", []string{"qa@example.com"}, []string{"beta@example.com"}, []string{"qa@example.com", "beta@example.com"}},
+		{"duplicates skipped", []string{"qa@example.com"}, []string{"qa@example.com", "beta@example.com", "beta@example.com"}, []string{"qa@example.com", "beta@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mergeGoogleGroups(tt.existing, tt.groups))
+		})
+	}
+}
+
+func TestConfigs_validateTestersGoogleGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		groups  string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"valid list", "qa@example.com|beta@example.com", false},
+		{"invalid email", "qa@example.com\nbeta", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Configs{TestersGoogleGroups: tt.groups, Logger: log.NewLogger()}
+			err := c.validateTestersGoogleGroups()
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package googleplay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type callTimeoutKey struct{}
+
+// deploymentContext returns the context of the deployment, which is cancelled with the given context, and after the
+// configured deployment timeout. API calls made with it are limited by the configured call timeout.
+func deploymentContext(ctx context.Context, configs Configs) (context.Context, context.CancelFunc) {
+	cancel := context.CancelFunc(func() {})
+	if configs.DeploymentTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(configs.DeploymentTimeout)*time.Minute)
+	}
+	return withCallTimeout(ctx, time.Duration(configs.CallTimeout)*time.Minute), cancel
+}
+
+// withCallTimeout sets the timeout of every API call made with the returned context. Zero disables the timeout.
+func withCallTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, callTimeoutKey{}, timeout)
+}
+
+// callContext returns the context of a single API call, limited by the call timeout of the given context.
+func callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout, ok := ctx.Value(callTimeoutKey{}).(time.Duration); ok && timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// sleepContext waits for the given duration, returning early with an error if the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// interruptionError returns the given error extended with the phase in which the deployment was cancelled or timed
+// out, or unchanged if neither happened.
+func interruptionError(ctx context.Context, phase string, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("Deployment timed out during phase: %s\n%w", phase, err)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("Deployment cancelled during phase: %s\n%w", phase, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("API call timed out during phase: %s\n%w", phase, err)
+	default:
+		return err
+	}
+}
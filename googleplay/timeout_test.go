@@ -0,0 +1,189 @@
+package googleplay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-google-play-deploy/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// interceptTransport lets a test act on the requests before they reach the emulator.
+type interceptTransport struct {
+	base      http.RoundTripper
+	intercept func(req *http.Request) error
+}
+
+func (t interceptTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	if err := t.intercept(req); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+func TestPublisher_executeEdit_interrupted(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	isUpload := func(req *http.Request) bool {
+		return strings.Contains(req.URL.Path, "/upload/")
+	}
+
+	tests := []struct {
+		name      string
+		intercept func(cancel context.CancelFunc) func(req *http.Request) error
+		ctx       func() (context.Context, context.CancelFunc)
+		wantErr   string
+		wantCode  FailureCode
+	}{
+		{
+			name: "cancelled during upload",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithCancel(context.Background())
+			},
+			intercept: func(cancel context.CancelFunc) func(req *http.Request) error {
+				return func(req *http.Request) error {
+					if isUpload(req) {
+						cancel()
+						return context.Canceled
+					}
+					return nil
+				}
+			},
+			wantErr:  "Deployment cancelled during phase: Upload apks or app bundles",
+			wantCode: FailureCancelled,
+		},
+		{
+			name: "call timeout during upload",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				return withCallTimeout(ctx, 10*time.Millisecond), cancel
+			},
+			intercept: func(context.CancelFunc) func(req *http.Request) error {
+				return func(req *http.Request) error {
+					if isUpload(req) {
+						<-req.Context().Done()
+						return req.Context().Err()
+					}
+					return nil
+				}
+			},
+			wantErr:  "API call timed out during phase: Upload apks or app bundles",
+			wantCode: FailureTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := emulator.NewServer()
+			server.AddApp(packageName)
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			client := &http.Client{Transport: interceptTransport{base: server, intercept: tt.intercept(cancel)}}
+
+			configs := Configs{PackageName: packageName, AppPath: writeBundles(t, "1"), Track: "beta", Logger: log.NewLogger()}
+			publisher := NewPublisher(log.NewLogger())
+			service, err := publisher.createService(context.Background(), configs, client)
+			require.NoError(t, err)
+
+			err = publisher.executeEdit(ctx, service, configs, Outputs{}, false, false)
+			require.Error(t, err)
+			assert.True(t, strings.HasPrefix(err.Error(), tt.wantErr), err.Error())
+			assert.Equal(t, tt.wantCode, ClassifyError(err).Code)
+			assert.Equal(t, 0, server.Commits(packageName))
+		})
+	}
+}
+
+func TestPublisher_executeEdit_deletesEditOnCancel(t *testing.T) {
+	const packageName = "io.bitrise.sample"
+	server := emulator.NewServer()
+	server.AddApp(packageName)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &http.Client{Transport: interceptTransport{base: server, intercept: func(req *http.Request) error {
+		if strings.HasSuffix(req.URL.Path, "/tracks/beta") {
+			cancel()
+			return context.Canceled
+		}
+		return nil
+	}}}
+
+	configs := Configs{PackageName: packageName, AppPath: writeBundles(t, "1"), Track: "beta", Logger: log.NewLogger()}
+	publisher := NewPublisher(log.NewLogger())
+	service, err := publisher.createService(context.Background(), configs, client)
+	require.NoError(t, err)
+
+	err = publisher.executeEdit(ctx, service, configs, Outputs{}, false, false)
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "Deployment cancelled during phase: Update track"), err.Error())
+	assert.Equal(t, 0, server.OpenEdits(packageName), "the open edit is deleted")
+}
+
+func Test_interruptionError(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	timedOut, cancelTimeout := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelTimeout()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want string
+	}{
+		{
+			name: "not interrupted",
+			ctx:  context.Background(),
+			err:  errors.New("Failed to commit edit"),
+			want: "Failed to commit edit",
+		},
+		{
+			name: "cancelled",
+			ctx:  cancelled,
+			err:  errors.New("Failed to commit edit"),
+			want: "Deployment cancelled during phase: Commit edit\nFailed to commit edit",
+		},
+		{
+			name: "deployment timed out",
+			ctx:  timedOut,
+			err:  errors.New("Failed to commit edit"),
+			want: "Deployment timed out during phase: Commit edit\nFailed to commit edit",
+		},
+		{
+			name: "call timed out",
+			ctx:  context.Background(),
+			err:  fmt.Errorf("Failed to commit edit, error: %w", context.DeadlineExceeded),
+			want: "API call timed out during phase: Commit edit\nFailed to commit edit, error: context deadline exceeded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := interruptionError(tt.ctx, "Commit edit", tt.err)
+			assert.EqualError(t, err, tt.want)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func Test_callContext(t *testing.T) {
+	ctx, cancel := callContext(context.Background())
+	defer cancel()
+	_, hasDeadline := ctx.Deadline()
+	assert.False(t, hasDeadline)
+
+	ctx, cancel = callContext(withCallTimeout(context.Background(), time.Minute))
+	defer cancel()
+	deadline, hasDeadline := ctx.Deadline()
+	require.True(t, hasDeadline)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+}
@@ -0,0 +1,125 @@
+package googleplay
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// formFactorPrefixes are the prefixes of the tracks of the form factors other than phones, like wear:production.
+var formFactorPrefixes = []string{"wear:", "tv:", "automotive:"}
+
+// maxTrackSuggestions limits the number of similar tracks suggested for an unknown track.
+const maxTrackSuggestions = 3
+
+// unknownTrackError is returned when the configured track is not among the tracks of the app.
+type unknownTrackError struct {
+	Track       string
+	Suggestions []string
+}
+
+func (e unknownTrackError) Error() string {
+	message := fmt.Sprintf("Track not found: %s.", e.Track)
+	if len(e.Suggestions) > 0 {
+		message += fmt.Sprintf(" Did you mean: %s?", strings.Join(e.Suggestions, ", "))
+	}
+	return message
+}
+
+// validateTrack checks that the track is one of the tracks of the app, so that an unknown track fails the deployment
+// before the apps are uploaded. The check is skipped if the tracks could not be listed.
+func validateTrack(track string, tracks []string) error {
+	if len(tracks) == 0 {
+		return nil
+	}
+	for _, name := range tracks {
+		if name == track {
+			return nil
+		}
+	}
+	return unknownTrackError{Track: track, Suggestions: similarTracks(track, tracks)}
+}
+
+// similarTracks returns the tracks closest to the given one by edit distance, ignoring case. The tracks of other form
+// factors are compared by their name, with a distance of one for the prefix, so that wear:beta is similar to beta.
+func similarTracks(track string, tracks []string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	maxDistance := len(track) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	prefix, name := splitFormFactor(strings.ToLower(track))
+	var candidates []candidate
+	for _, other := range tracks {
+		otherPrefix, otherName := splitFormFactor(strings.ToLower(other))
+		distance := editDistance(strings.ToLower(track), strings.ToLower(other))
+		if otherPrefix != prefix {
+			if d := editDistance(name, otherName) + 1; d < distance {
+				distance = d
+			}
+		}
+		if distance <= maxDistance {
+			candidates = append(candidates, candidate{name: other, distance: distance})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	var suggestions []string
+	for i := 0; i < len(candidates) && i < maxTrackSuggestions; i++ {
+		suggestions = append(suggestions, candidates[i].name)
+	}
+	return suggestions
+}
+
+// splitFormFactor splits the form factor prefix, if any, from the name of the track.
+func splitFormFactor(track string) (string, string) {
+	for _, prefix := range formFactorPrefixes {
+		if strings.HasPrefix(track, prefix) {
+			return prefix, strings.TrimPrefix(track, prefix)
+		}
+	}
+	return "", track
+}
+
+// editDistance returns the Levenshtein distance of the given strings.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	m := a
+	if b < m {
+		m = b
+	}
+	if c < m {
+		m = c
+	}
+	return m
+}
@@ -0,0 +1,60 @@
+package googleplay
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validateTrack(t *testing.T) {
+	tracks := []string{"production", "beta", "alpha", "internal", "wear:production", "wear:internal", "tv:beta", "automotive:internal", "qa-team"}
+
+	tests := []struct {
+		name    string
+		track   string
+		tracks  []string
+		wantErr string
+	}{
+		{name: "known track", track: "beta"},
+		{name: "known form factor track", track: "wear:production"},
+		{name: "tracks could not be listed", track: "beta", tracks: []string{}},
+		{name: "misspelled track", track: "prodcution", wantErr: "Track not found: prodcution. Did you mean: production, wear:production?"},
+		{name: "different case", track: "Beta", wantErr: "Track not found: Beta. Did you mean: beta, tv:beta?"},
+		{name: "missing form factor prefix", track: "wear:beta", wantErr: "Track not found: wear:beta. Did you mean: beta, tv:beta?"},
+		{name: "misspelled form factor prefix", track: "automotve:internal", wantErr: "Track not found: automotve:internal. Did you mean: automotive:internal?"},
+		{name: "custom track", track: "qa_team", wantErr: "Track not found: qa_team. Did you mean: qa-team?"},
+		{name: "no similar track", track: "staging", wantErr: "Track not found: staging."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			available := tracks
+			if tt.tracks != nil {
+				available = tt.tracks
+			}
+			err := validateTrack(tt.track, available)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+			assert.Equal(t, FailureTrackNotFound, ClassifyError(err).Code)
+		})
+	}
+}
+
+func Test_editDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"beta", "", 4},
+		{"beta", "beta", 0},
+		{"bta", "beta", 1},
+		{"prodcution", "production", 2},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, editDistance(tt.a, tt.b), "%s -> %s", tt.a, tt.b)
+	}
+}
@@ -0,0 +1,143 @@
+package googleplay
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"google.golang.org/api/androidpublisher/v3"
+	"google.golang.org/api/option"
+)
+
+// newTransport creates the HTTP transport of every request the step sends, using the configured HTTPS proxy and
+// CA bundle. Without a configured proxy the proxy environment variables (HTTPS_PROXY, NO_PROXY) are respected.
+func newTransport(configs Configs) (*http.Transport, error) {
+	transport := cleanhttp.DefaultPooledTransport()
+
+	if configs.HTTPSProxy != "" {
+		proxyURL, err := url.Parse(string(configs.HTTPSProxy))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse proxy url, error: %s", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if configs.CABundlePath != "" {
+		pem, err := os.ReadFile(configs.CABundlePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle (%s), error: %s", configs.CABundlePath, err)
+		}
+
+		// The bundle extends the system certificates, so Google endpoints not behind the proxy still work.
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil || rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in CA bundle (%s)", configs.CABundlePath)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+	}
+
+	return transport, nil
+}
+
+// createService creates the Google Play Developer API service on top of the given client, using the configured API
+// base URL and upload URL if set.
+func (p *Publisher) createService(ctx context.Context, configs Configs, client *http.Client) (*androidpublisher.Service, error) {
+	opts := []option.ClientOption{option.WithHTTPClient(client)}
+
+	if configs.APIBaseURL != "" {
+		p.logger.Printf(" using API base URL: %s", configs.APIBaseURL)
+		opts = append(opts, option.WithEndpoint(withTrailingSlash(configs.APIBaseURL)))
+	}
+
+	if configs.APIUploadURL != "" {
+		uploadURL, err := url.Parse(configs.APIUploadURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse upload url, error: %s", err)
+		}
+		p.logger.Printf(" using API upload URL: %s", configs.APIUploadURL)
+
+		base := client.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		uploadClient := *client
+		uploadClient.Transport = uploadEndpointTransport{base: base, uploadURL: uploadURL, apiBasePaths: configs.apiBasePaths()}
+		opts[0] = option.WithHTTPClient(&uploadClient)
+	}
+
+	return androidpublisher.NewService(ctx, opts...)
+}
+
+// uploadEndpointTransport sends the media upload requests to a different host than the rest of the API calls.
+type uploadEndpointTransport struct {
+	base      http.RoundTripper
+	uploadURL *url.URL
+	// apiBasePaths are the path prefixes of the configured API URLs, see Configs.apiBasePaths.
+	apiBasePaths []string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t uploadEndpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	relativePath, ok := uploadPath(req.URL, t.apiBasePaths)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	rewritten := req.Clone(req.Context())
+	rewritten.URL.Scheme = t.uploadURL.Scheme
+	rewritten.URL.Host = t.uploadURL.Host
+	rewritten.URL.Path = strings.TrimSuffix(t.uploadURL.Path, "/") + relativePath
+	rewritten.Host = ""
+	return t.base.RoundTrip(rewritten)
+}
+
+// isUploadRequest returns true for the media upload endpoints of the API, see uploadPath.
+func isUploadRequest(u *url.URL, apiBasePaths []string) bool {
+	_, ok := uploadPath(u, apiBasePaths)
+	return ok
+}
+
+// uploadPath returns the path of a media upload endpoint of the API relative to the API base path it is sent to, for
+// example /upload/androidpublisher/v3/applications/{packageName}/edits/{editId}/bundles for
+// https://proxy.example/gp/upload/androidpublisher/v3/applications/{packageName}/edits/{editId}/bundles if the API
+// base URL is https://proxy.example/gp/. Returns false if the URL is not a media upload.
+func uploadPath(u *url.URL, apiBasePaths []string) (string, bool) {
+	for _, basePath := range append([]string{""}, apiBasePaths...) {
+		relativePath := strings.TrimPrefix(u.Path, strings.TrimSuffix(basePath, "/"))
+		if strings.HasPrefix(relativePath, "/upload/") || strings.HasPrefix(relativePath, "/resumable/upload/") {
+			return relativePath, true
+		}
+	}
+	return "", false
+}
+
+// apiBasePaths returns the path prefixes of the configured API base URL and upload URL, like /gp/ of
+// https://proxy.example/gp/. The API is served from the root of the default URLs.
+func (c Configs) apiBasePaths() []string {
+	var basePaths []string
+	for _, rawURL := range []string{c.APIBaseURL, c.APIUploadURL} {
+		if rawURL == "" {
+			continue
+		}
+		if u, err := url.Parse(rawURL); err == nil && strings.Trim(u.Path, "/") != "" {
+			basePaths = append(basePaths, u.Path)
+		}
+	}
+	return basePaths
+}
+
+func withTrailingSlash(s string) string {
+	if strings.HasSuffix(s, "/") {
+		return s
+	}
+	return s + "/"
+}
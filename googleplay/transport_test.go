@@ -0,0 +1,138 @@
+package googleplay
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-steputils/stepconf"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/androidpublisher/v3"
+	"google.golang.org/api/googleapi"
+)
+
+func Test_createService_customEndpoints(t *testing.T) {
+	tests := []struct {
+		name           string
+		apiPath        string
+		uploadPath     string
+		wantAPIPath    string
+		wantUploadPath string
+	}{
+		{
+			name:           "root",
+			wantAPIPath:    "/androidpublisher/v3/applications/com.example/edits",
+			wantUploadPath: "/upload/androidpublisher/v3/applications/com.example/edits/edit-1/bundles",
+		},
+		{
+			name:           "path prefixes",
+			apiPath:        "/gp/",
+			uploadPath:     "/uploads",
+			wantAPIPath:    "/gp/androidpublisher/v3/applications/com.example/edits",
+			wantUploadPath: "/uploads/upload/androidpublisher/v3/applications/com.example/edits/edit-1/bundles",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var apiPaths, uploadPaths []string
+			api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				apiPaths = append(apiPaths, r.URL.Path)
+				_, _ = w.Write([]byte(`{"id": "edit-1"}`))
+			}))
+			defer api.Close()
+			upload := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				uploadPaths = append(uploadPaths, r.URL.Path)
+				_, _ = w.Write([]byte(`{"versionCode": 42}`))
+			}))
+			defer upload.Close()
+
+			configs := Configs{APIBaseURL: api.URL + tt.apiPath, APIUploadURL: upload.URL + tt.uploadPath}
+			service, err := NewPublisher(log.NewLogger()).createService(context.Background(), configs, &http.Client{})
+			require.NoError(t, err)
+
+			edit, err := service.Edits.Insert("com.example", &androidpublisher.AppEdit{}).Do()
+			require.NoError(t, err)
+			assert.Equal(t, "edit-1", edit.Id)
+
+			bundle, err := service.Edits.Bundles.Upload("com.example", edit.Id).Media(strings.NewReader("aab"), googleapi.ContentType("application/octet-stream")).Do()
+			require.NoError(t, err)
+			assert.Equal(t, int64(42), bundle.VersionCode)
+
+			assert.Equal(t, []string{tt.wantAPIPath}, apiPaths)
+			assert.Equal(t, []string{tt.wantUploadPath}, uploadPaths)
+		})
+	}
+}
+
+func Test_uploadPath(t *testing.T) {
+	tests := []struct {
+		url          string
+		apiBasePaths []string
+		want         string
+		wantOK       bool
+	}{
+		{url: "https://androidpublisher.googleapis.com/upload/androidpublisher/v3/applications/io.bitrise/edits/1/bundles", want: "/upload/androidpublisher/v3/applications/io.bitrise/edits/1/bundles", wantOK: true},
+		{url: "https://androidpublisher.googleapis.com/resumable/upload/androidpublisher/v3/applications/io.bitrise/edits/1/bundles", want: "/resumable/upload/androidpublisher/v3/applications/io.bitrise/edits/1/bundles", wantOK: true},
+		{url: "https://proxy.example/gp/upload/androidpublisher/v3/applications/io.bitrise/edits/1/apks", apiBasePaths: []string{"/gp/"}, want: "/upload/androidpublisher/v3/applications/io.bitrise/edits/1/apks", wantOK: true},
+		{url: "https://proxy.example/gp/upload/androidpublisher/v3/applications/io.bitrise/edits/1/apks"},
+		{url: "https://proxy.example/gp/androidpublisher/v3/applications/upload/edits", apiBasePaths: []string{"/gp/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+			got, ok := uploadPath(u, tt.apiBasePaths)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_newTransport_proxy(t *testing.T) {
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.Host
+	}))
+	defer proxy.Close()
+
+	transport, err := newTransport(Configs{HTTPSProxy: stepconf.Secret(proxy.URL)})
+	require.NoError(t, err)
+
+	resp, err := (&http.Client{Transport: transport}).Get("http://play.example.com/key.json")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "play.example.com", proxiedHost)
+}
+
+func Test_newTransport_caBundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caPth := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caPth, caPEM, 0600))
+
+	withoutCA, err := newTransport(Configs{})
+	require.NoError(t, err)
+	_, err = (&http.Client{Transport: withoutCA}).Get(server.URL)
+	assert.Error(t, err)
+
+	withCA, err := newTransport(Configs{CABundlePath: caPth})
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: withCA}).Get(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	invalidPth := filepath.Join(t.TempDir(), "invalid.pem")
+	require.NoError(t, os.WriteFile(invalidPth, []byte("not a certificate"), 0600))
+	_, err = newTransport(Configs{CABundlePath: invalidPth})
+	assert.Error(t, err)
+}
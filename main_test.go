@@ -1,73 +0,0 @@
-package main
-
-import (
-	"testing"
-
-	"github.com/stretchr/testify/require"
-)
-
-func TestParseURI(t *testing.T) {
-
-	t.Log("parseURI - file://../../../../../../Downloads/key.json")
-	{
-		keyPth, isRemote, err := parseURI("file://../../../../../../Downloads/key.json")
-		require.NoError(t, err)
-
-		require.Equal(t, "../../../../../../Downloads/key.json", keyPth)
-		require.Equal(t, false, isRemote)
-	}
-
-	t.Log("parseURI - file://./")
-	{
-		keyPth, isRemote, err := parseURI("file://./testfolder/key.json")
-		require.NoError(t, err)
-
-		require.Equal(t, "./testfolder/key.json", keyPth)
-		require.Equal(t, false, isRemote)
-	}
-
-	t.Log("parseURI - file:///")
-	{
-		keyPth, isRemote, err := parseURI("file:///testfolder/key.json")
-		require.NoError(t, err)
-
-		require.Equal(t, "/testfolder/key.json", keyPth)
-		require.Equal(t, false, isRemote)
-	}
-
-	t.Log("parseURI - http://")
-	{
-		keyPth, isRemote, err := parseURI("http://testdomain.com/testsub/key.json")
-		require.NoError(t, err)
-
-		require.Equal(t, "http://testdomain.com/testsub/key.json", keyPth)
-		require.Equal(t, true, isRemote)
-	}
-
-	t.Log("parseURI - https://")
-	{
-		keyPth, isRemote, err := parseURI("https://testdomain.com/testsub/key.json")
-		require.NoError(t, err)
-
-		require.Equal(t, "https://testdomain.com/testsub/key.json", keyPth)
-		require.Equal(t, true, isRemote)
-	}
-
-	t.Log("parseURI - ./")
-	{
-		keyPth, isRemote, err := parseURI("./user/test/key.json")
-		require.NoError(t, err)
-
-		require.Equal(t, "./user/test/key.json", keyPth)
-		require.Equal(t, false, isRemote)
-	}
-
-	t.Log("parseURI - /")
-	{
-		keyPth, isRemote, err := parseURI("/user/test/key.json")
-		require.NoError(t, err)
-
-		require.Equal(t, "/user/test/key.json", keyPth)
-		require.Equal(t, false, isRemote)
-	}
-}